@@ -0,0 +1,95 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Combine values with an aggregate function: sum, avg, min, max, count or pN (percentile N)
+ */
+func aggregate(function string, values []float64) (float64, bool) {
+	switch function {
+	case "count":
+		return float64(len(values)), true
+	case "sum", "avg", "min", "max":
+		if len(values) == 0 {
+			return math.NaN(), true
+		}
+		result := values[0]
+		sum := float64(0)
+		for _, value := range values {
+			sum += value
+			if function == "min" {
+				result = math.Min(result, value)
+			} else if function == "max" {
+				result = math.Max(result, value)
+			}
+		}
+		if function == "sum" {
+			return sum, true
+		}
+		if function == "avg" {
+			return sum / float64(len(values)), true
+		}
+		return result, true
+	}
+	if strings.HasPrefix(function, "p") {
+		p, err := strconv.ParseFloat(function[1:], 64)
+		if err == nil && p >= 0 && p <= 100 {
+			return percentile(values, p), true
+		}
+	}
+	return math.NaN(), false
+}
+
+/**
+ * Aggregate the found items per bucket and write the values for the virtual host
+ */
+func processAggregate(session zabbix.Session, items []zabbix.ItemResponseElement, configuration zabbix.AggregateConfiguration) {
+	interval := time.Duration(configuration.Interval) * time.Second
+	if interval == 0 {
+		interval = time.Minute
+	}
+	lookback := time.Duration(configuration.Lookback) * time.Second
+	if lookback < interval {
+		lookback = interval
+	}
+	count := int(lookback / interval)
+	// the average if no outputs are configured
+	outputs := outputsOrDefault(configuration.Outputs, zabbix.ItemConfiguration{}, "avg")
+	end := time.Now().Truncate(interval)
+
+	// each item contributes its average per bucket, gaps are not filled
+	sampler := newResampler(zabbix.ResampleConfiguration{Aggregation: "avg", Fill: "none"}, interval)
+	buckets := make([][]float64, count)
+	for _, item := range items {
+		series := loadSeries(session, item, end.Add(-lookback), end, "history")
+		for i, value := range sampler.resample(series, end, count) {
+			if !math.IsNaN(value) {
+				buckets[i] = append(buckets[i], value)
+			}
+		}
+	}
+
+	for i, values := range buckets {
+		if len(values) == 0 {
+			continue
+		}
+		timestamp := end.Add(-interval * time.Duration(count-1-i))
+		for _, output := range outputs {
+			value, known := aggregate(output.Type, values)
+			if !known {
+				Log.Warn("unknown aggregate function", "function", output.Type)
+				continue
+			}
+			if key, ok := derivedKey(configuration.Key, output.Postfix, output.Key); ok && !math.IsNaN(value) {
+				addSenderLine(configuration.Host, key, timestamp, value)
+			}
+		}
+	}
+	Log.Info("aggregated items", "host", configuration.Host, "key", configuration.Key, "items", len(items), "buckets", count)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	values := []float64{4, 1, 3, 2}
+	expected := map[string]float64{"sum": 10, "avg": 2.5, "min": 1, "max": 4, "count": 4, "p50": 2.5, "p100": 4}
+	for function, result := range expected {
+		value, known := aggregate(function, values)
+		assert.True(t, known, function)
+		assert.Equal(t, result, value, function)
+	}
+}
+
+func TestAggregateUnknown(t *testing.T) {
+	_, known := aggregate("median", []float64{1})
+	assert.False(t, known)
+	_, known = aggregate("p101", []float64{1})
+	assert.False(t, known)
+	value, known := aggregate("sum", nil)
+	assert.True(t, known)
+	assert.True(t, math.IsNaN(value))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"time"
+)
+
+// calendars excluding dates from the past weeks
+var calendars = make([]zabbix.Calendar, 0)
+
+// Host ID to the names of its host groups
+var hostGroups = make(map[string][]string)
+
+/**
+ * Load the configured calendars
+ */
+func loadCalendars(configurations []zabbix.CalendarConfiguration) error {
+	for _, configuration := range configurations {
+		calendar, err := zabbix.LoadCalendar(configuration)
+		if err != nil {
+			return err
+		}
+		Log.Info("loaded calendar", "name", calendar.Name, "groups", calendar.Groups, "exclusions", len(calendar.Exclusions))
+		calendars = append(calendars, calendar)
+	}
+	return nil
+}
+
+/**
+ * True if a calendar applying to the host excludes t
+ */
+func excluded(hostID string, t time.Time) bool {
+	for _, calendar := range calendars {
+		if calendar.AppliesTo(hostGroups[hostID]) && calendar.Excludes(t) {
+			return true
+		}
+	}
+	return false
+}
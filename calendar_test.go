@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestExcluded(t *testing.T) {
+	defer func() { calendars = make([]zabbix.Calendar, 0) }()
+	err := loadCalendars([]zabbix.CalendarConfiguration{
+		{Name: "all", Dates: []string{"2020-12-25"}},
+		{Name: "shops", Groups: []string{"Shops"}, Dates: []string{"2020-11-27"}},
+	})
+	assert.Nil(t, err)
+	hostGroups["1"] = []string{"Shops"}
+	hostGroups["2"] = []string{"Linux servers"}
+
+	christmas := time.Date(2020, 12, 25, 10, 0, 0, 0, time.Local)
+	blackFriday := time.Date(2020, 11, 27, 10, 0, 0, 0, time.Local)
+	assert.True(t, excluded("1", christmas))
+	assert.True(t, excluded("2", christmas))
+	assert.True(t, excluded("1", blackFriday))
+	assert.False(t, excluded("2", blackFriday))
+	assert.False(t, excluded("2", christmas.AddDate(0, 0, 1)))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"time"
+)
+
+/**
+ * Search a level shift in values after the first reference values.
+ * Returns the index of the first shifted value or -1, and the shift relative to the reference mean.
+ * k and h are in standard deviations of the reference values.
+ */
+func detectChange(values []float64, reference int, method string, k float64, h float64) (int, float64) {
+	if reference < 2 || reference >= len(values) {
+		return -1, math.NaN()
+	}
+	center := mean(values[:reference])
+	deviation := math.Sqrt(meanSquare(subtract(values[:reference], center)))
+	if deviation == 0 {
+		// flat reference: any difference is a shift
+		deviation = math.SmallestNonzeroFloat64
+	}
+
+	var start int
+	if method == "pagehinkley" {
+		start = pageHinkley(values[reference:], center, k*deviation, h*deviation)
+	} else {
+		start = cusum(values[reference:], center, deviation, k, h)
+	}
+	if start < 0 {
+		return -1, math.NaN()
+	}
+	start += reference
+	return start, mean(values[start:]) - center
+}
+
+/**
+ * Two sided tabular CUSUM on standardized values. Returns the start of the run triggering the alarm
+ */
+func cusum(values []float64, center float64, deviation float64, k float64, h float64) int {
+	var upper, lower float64
+	upperStart, lowerStart := 0, 0
+	for i, value := range values {
+		z := (value - center) / deviation
+		if upper == 0 {
+			upperStart = i
+		}
+		if lower == 0 {
+			lowerStart = i
+		}
+		upper = math.Max(0, upper+z-k)
+		lower = math.Max(0, lower-z-k)
+		if upper > h {
+			return upperStart
+		}
+		if lower > h {
+			return lowerStart
+		}
+	}
+	return -1
+}
+
+/**
+ * Two sided Page-Hinkley test. Returns the index after the extreme of the cumulative sum
+ */
+func pageHinkley(values []float64, center float64, delta float64, lambda float64) int {
+	var up, down, minimumUp, maximumDown float64
+	upStart, downStart := 0, 0
+	for i, value := range values {
+		up += value - center - delta
+		down += value - center + delta
+		if up < minimumUp {
+			minimumUp = up
+			upStart = i + 1
+		}
+		if down > maximumDown {
+			maximumDown = down
+			downStart = i + 1
+		}
+		if up-minimumUp > lambda {
+			return upStart
+		}
+		if maximumDown-down > lambda {
+			return downStart
+		}
+	}
+	return -1
+}
+
+func subtract(values []float64, offset float64) []float64 {
+	result := make([]float64, len(values))
+	for i, value := range values {
+		result[i] = value - offset
+	}
+	return result
+}
+
+/**
+ * Detect a level shift in the recent item history
+ */
+func processChangePoint(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.ChangePoint
+	lookback := time.Duration(configuration.Lookback) * time.Second
+	reference := time.Duration(configuration.Reference) * time.Second
+	if reference == 0 {
+		reference = lookback / 2
+	}
+	k := configuration.K
+	if k == 0 {
+		k = 0.5
+	}
+	h := configuration.H
+	if h == 0 {
+		h = 5
+	}
+
+	now := time.Now()
+	from := now.Add(-lookback)
+	series := loadSeries(session, item, from, now, "history")
+	values := make([]float64, len(series))
+	references := 0
+	for i, s := range series {
+		values[i] = s.value
+		if s.time.Before(from.Add(reference)) {
+			references++
+		}
+	}
+	if references < 2 || references == len(series) {
+		Log.Warn("not enough history for change point detection", "item", item.ItemID, "reference", references, "samples", len(series))
+		return
+	}
+
+	index, magnitude := detectChange(values, references, configuration.Method, k, h)
+	result := map[string]float64{"changetime": math.NaN(), "magnitude": math.NaN(), "shifted": 0}
+	if index >= 0 {
+		result["changetime"] = float64(series[index].time.Unix())
+		result["magnitude"] = magnitude
+		result["shifted"] = 1
+		Log.Info("level shift detected", "item", item.ItemID, "at", series[index].time.Format("Mon 01-02 15:04:05"), "magnitude", magnitude)
+	}
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, "changetime", "magnitude", "shifted")
+	emitOutputs(item, outputs, series[len(series)-1].time, result)
+}
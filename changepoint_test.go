@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func steppedSeries() []float64 {
+	values := make([]float64, 0, 100)
+	for i := 0; i < 100; i++ {
+		value := 10 + float64(i%3) - 1
+		if i >= 70 {
+			value += 5
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestCUSUMFindsStep(t *testing.T) {
+	index, magnitude := detectChange(steppedSeries(), 50, "cusum", 0.5, 5)
+	assert.InDelta(t, 70, index, 1)
+	assert.InDelta(t, 5, magnitude, 0.5)
+}
+
+func TestPageHinkleyFindsStep(t *testing.T) {
+	index, magnitude := detectChange(steppedSeries(), 50, "pagehinkley", 0.5, 5)
+	assert.InDelta(t, 70, index, 1)
+	assert.InDelta(t, 5, magnitude, 0.5)
+}
+
+func TestChangeDownwards(t *testing.T) {
+	values := steppedSeries()
+	for i := range values {
+		values[i] = -values[i]
+	}
+	index, magnitude := detectChange(values, 50, "cusum", 0.5, 5)
+	assert.InDelta(t, 70, index, 1)
+	assert.InDelta(t, -5, magnitude, 0.5)
+}
+
+func TestNoChange(t *testing.T) {
+	index, magnitude := detectChange(steppedSeries()[:70], 35, "cusum", 0.5, 5)
+	assert.Equal(t, -1, index)
+	assert.True(t, math.IsNaN(magnitude))
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+/**
+ * Correlation of a candidate item with the anchor item on the same host
+ */
+type correlationResult struct {
+	Host        string  `json:"host"`
+	Key         string  `json:"key"`
+	ItemID      string  `json:"itemid"`
+	Correlation float64 `json:"correlation"`
+	Lag         int64   `json:"lag"`     // seconds the candidate lags behind the anchor. negative if it leads
+	Samples     int     `json:"samples"` // buckets with values of both items
+}
+
+/**
+ * Pearson correlation coefficient. NaN if a series is constant
+ */
+func pearson(x []float64, y []float64) float64 {
+	if len(x) < 2 {
+		return math.NaN()
+	}
+	mx, my := mean(x), mean(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+		syy += (y[i] - my) * (y[i] - my)
+	}
+	if sxx == 0 || syy == 0 {
+		return math.NaN()
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+/**
+ * Ranks starting with 1, ties get the average rank
+ */
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+	result := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		for k := i; k <= j; k++ {
+			result[order[k]] = float64(i+j)/2 + 1
+		}
+		i = j + 1
+	}
+	return result
+}
+
+/**
+ * Spearman rank correlation: Pearson correlation of the ranks
+ */
+func spearman(x []float64, y []float64) float64 {
+	return pearson(ranks(x), ranks(y))
+}
+
+/**
+ * Correlation of anchor[i] with candidate[i+lag] over the buckets where both have values
+ */
+func laggedCorrelation(anchor []float64, candidate []float64, lag int, method string) (float64, int) {
+	x := make([]float64, 0, len(anchor))
+	y := make([]float64, 0, len(anchor))
+	for i := range anchor {
+		j := i + lag
+		if j < 0 || j >= len(candidate) || math.IsNaN(anchor[i]) || math.IsNaN(candidate[j]) {
+			continue
+		}
+		x = append(x, anchor[i])
+		y = append(y, candidate[j])
+	}
+	if method == "spearman" {
+		return spearman(x, y), len(x)
+	}
+	return pearson(x, y), len(x)
+}
+
+/**
+ * Strongest correlation (by absolute value) for lags from -lags to lags buckets.
+ * Lags with less than minSamples shared buckets are ignored, a few points correlate by chance
+ */
+func bestCorrelation(anchor []float64, candidate []float64, lags int, method string, minSamples int) (float64, int, int) {
+	best, bestLag, bestSamples := math.NaN(), 0, 0
+	for lag := -lags; lag <= lags; lag++ {
+		correlation, samples := laggedCorrelation(anchor, candidate, lag, method)
+		if samples < minSamples {
+			continue
+		}
+		if !math.IsNaN(correlation) && (math.IsNaN(best) || math.Abs(correlation) > math.Abs(best)) {
+			best, bestLag, bestSamples = correlation, lag, samples
+		}
+	}
+	return best, bestLag, bestSamples
+}
+
+/**
+ * Sort by absolute correlation, strongest first, and keep the top entries (all if top is 0)
+ */
+func rankCorrelations(results []correlationResult, top int) []correlationResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return math.Abs(results[i].Correlation) > math.Abs(results[j].Correlation)
+	})
+	if top > 0 && len(results) > top {
+		results = results[:top]
+	}
+	return results
+}
+
+/**
+ * Ranked correlations as aligned text table (default) or JSON
+ */
+func formatCorrelations(results []correlationResult, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(results, "", "  ")
+	case "", "text":
+	default:
+		return nil, fmt.Errorf("unknown correlation format %s", format)
+	}
+	var b bytes.Buffer
+	writer := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "RANK\tHOST\tKEY\tCORRELATION\tLAG\tSAMPLES")
+	for i, result := range results {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%.3f\t%s\t%d\n", i+1, result.Host, result.Key, result.Correlation,
+			time.Duration(result.Lag)*time.Second, result.Samples)
+	}
+	writer.Flush()
+	return b.Bytes(), nil
+}
+
+/**
+ * Correlate the anchor item with the items of the configured filters on each host
+ */
+func correlate(session zabbix.Session, configuration zabbix.Configuration, anchorKey string) []correlationResult {
+	settings := configuration.Correlation
+	window := time.Duration(settings.Window) * time.Second
+	if window == 0 {
+		window = time.Hour * 24
+	}
+	interval := time.Duration(settings.Interval) * time.Second
+	if interval == 0 {
+		interval = time.Minute * 5
+	}
+	count := int(window / interval)
+	minSamples := settings.MinSamples
+	if minSamples == 0 {
+		minSamples = 10
+	}
+	sampler := newResampler(zabbix.ResampleConfiguration{Fill: "none", Timezone: "Local"}, interval)
+	end := sampler.align(time.Now())
+	buckets := func(item zabbix.ItemResponseElement) []float64 {
+		return sampler.resample(loadSeries(session, item, end.Add(-window), end, "history"), end, count)
+	}
+
+	query := session.NewItemQuery(keysFromMap(hosts), map[string][]string{"key_": {anchorKey}}, nil)
+	anchorItems := query.Query()
+	anchorHosts := make([]string, 0)
+	anchorIDs := make(map[string]bool)
+	for _, anchor := range anchorItems {
+		anchorHosts = append(anchorHosts, anchor.HostID)
+		anchorIDs[anchor.ItemID] = true
+	}
+	if len(anchorItems) == 0 {
+		Log.Warn("anchor item not found", "key", anchorKey, "hosts", keysFromMap(hosts))
+		return make([]correlationResult, 0)
+	}
+
+	candidates := make([][]zabbix.ItemResponseElement, len(configuration.Items))
+	for i, itemFilter := range configuration.Items {
+		query := session.NewItemQuery(anchorHosts, itemFilter.Filter, itemFilter.Search)
+		query.SearchWildcardsEnabled = true
+		candidates[i] = query.Query()
+		if len(itemFilter.Parameters) > 0 {
+			candidates[i] = filterByParameters(candidates[i], itemFilter.Parameters)
+		}
+	}
+
+	// the anchor uses the preprocessing of the first filter matching it, like a candidate would
+	anchors := make(map[string][]float64)
+	for _, anchor := range anchorItems {
+		var preprocessing zabbix.PreprocessingConfiguration
+		for i, items := range candidates {
+			if containsItem(items, anchor.ItemID) {
+				preprocessing = configuration.Items[i].Preprocessing
+				break
+			}
+		}
+		reset := applyPreprocessing([]zabbix.ItemResponseElement{anchor}, preprocessing)
+		anchors[anchor.HostID] = buckets(anchor)
+		reset()
+	}
+
+	results := make([]correlationResult, 0)
+	seen := make(map[string]bool)
+	for i, items := range candidates {
+		// raw counters all rise and correlate with each other
+		reset := applyPreprocessing(items, configuration.Items[i].Preprocessing)
+		for _, item := range items {
+			if seen[item.ItemID] || anchorIDs[item.ItemID] || item.ValueType == 1 || item.ValueType == 2 || item.ValueType == 4 {
+				continue
+			}
+			seen[item.ItemID] = true
+			correlation, lag, samples := bestCorrelation(anchors[item.HostID], buckets(item), settings.Lags, settings.Method, minSamples)
+			if math.IsNaN(correlation) {
+				Log.Debug("no correlation", "item", item.ItemID, "key", item.Key)
+				continue
+			}
+			results = append(results, correlationResult{
+				Host: hosts[item.HostID], Key: item.Key, ItemID: item.ItemID,
+				Correlation: correlation, Lag: int64(time.Duration(lag) * interval / time.Second), Samples: samples,
+			})
+		}
+		reset()
+	}
+	return rankCorrelations(results, settings.Top)
+}
+
+func containsItem(items []zabbix.ItemResponseElement, itemID string) bool {
+	for _, item := range items {
+		if item.ItemID == itemID {
+			return true
+		}
+	}
+	return false
+}
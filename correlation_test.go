@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPearson(t *testing.T) {
+	assert.InDelta(t, 1.0, pearson([]float64{1, 2, 3, 4}, []float64{2, 4, 6, 8}), 1e-9)
+	assert.InDelta(t, -1.0, pearson([]float64{1, 2, 3, 4}, []float64{8, 6, 4, 2}), 1e-9)
+	assert.True(t, math.IsNaN(pearson([]float64{1, 2, 3}, []float64{5, 5, 5})))
+	assert.True(t, math.IsNaN(pearson([]float64{1}, []float64{1})))
+}
+
+func TestSpearman(t *testing.T) {
+	assert.Equal(t, []float64{1, 2.5, 2.5, 4}, ranks([]float64{1, 5, 5, 9}))
+	// monotonic but not linear
+	x := []float64{1, 2, 3, 4, 5}
+	y := []float64{1, 4, 9, 16, 1000}
+	assert.InDelta(t, 1.0, spearman(x, y), 1e-9)
+	assert.Less(t, pearson(x, y), 0.9)
+}
+
+func TestBestCorrelation(t *testing.T) {
+	anchor := []float64{0, 0, 5, 0, 0, 0, 7, 0, 0, 1, 0, 0}
+	// candidate follows the anchor two buckets later
+	candidate := []float64{0, 0, 0, 0, 5, 0, 0, 0, 7, 0, 0, 1}
+	correlation, lag, samples := bestCorrelation(anchor, candidate, 3, "pearson", 10)
+	assert.InDelta(t, 1.0, correlation, 1e-9)
+	assert.Equal(t, 2, lag)
+	assert.Equal(t, 10, samples)
+
+	_, lag, _ = bestCorrelation(anchor, candidate, 0, "pearson", 10)
+	assert.Equal(t, 0, lag)
+
+	// two shared buckets always correlate perfectly
+	sparse := []float64{1, 2, math.NaN(), math.NaN(), math.NaN()}
+	other := []float64{5, 3, 9, 1, 4}
+	correlation, _, _ = bestCorrelation(sparse, other, 0, "pearson", 0)
+	assert.InDelta(t, -1.0, correlation, 1e-9)
+	correlation, _, samples = bestCorrelation(sparse, other, 0, "pearson", 10)
+	assert.True(t, math.IsNaN(correlation))
+	assert.Equal(t, 0, samples)
+
+	// missing buckets are ignored
+	gaps := append([]float64(nil), anchor...)
+	gaps[0] = math.NaN()
+	_, samples = laggedCorrelation(gaps, anchor, 0, "spearman")
+	assert.Equal(t, 11, samples)
+}
+
+func TestCorrelationOfCounters(t *testing.T) {
+	// independent traffic, the raw counters rise together anyway
+	a := []sample{}
+	b := []sample{}
+	totalA, totalB := 0.0, 0.0
+	for i := 0; i < 48; i++ {
+		totalA += float64(100 + (i*37)%50)
+		totalB += float64(100 + (i*11)%7*10)
+		a = append(a, sample{time: time.Unix(int64(300*(i+1)), 0), value: totalA})
+		b = append(b, sample{time: time.Unix(int64(300*(i+1)), 0), value: totalB})
+	}
+	sampler := resampler{interval: 300 * time.Second, aggregation: "avg", fill: "none"}
+	end := time.Unix(300*48, 0)
+	raw, _, _ := bestCorrelation(sampler.resample(a, end, 48), sampler.resample(b, end, 48), 0, "pearson", 10)
+	assert.Greater(t, raw, 0.99)
+
+	delta := zabbix.PreprocessingConfiguration{Mode: "delta"}
+	converted, _, _ := bestCorrelation(sampler.resample(preprocess(a, delta), end, 48),
+		sampler.resample(preprocess(b, delta), end, 48), 0, "pearson", 10)
+	assert.Less(t, math.Abs(converted), 0.5)
+}
+
+func TestCorrelateAnchorCounter(t *testing.T) {
+	defer func(original map[string]string) { hosts = original }(hosts)
+	hosts = map[string]string{"1": "web"}
+	// the requests counter of the anchor and the request rate per bucket of the candidate
+	increase := func(i int) float64 { return float64(100 + (i*37)%50) }
+	session := fakeAPI(t, func(method string, params map[string]interface{}) interface{} {
+		if method == "item.get" {
+			key := params["filter"].(map[string]interface{})["key_"].([]interface{})[0].(string)
+			ids := map[string]string{"app.requests.total": "100", "app.requests.rate": "101"}
+			return []map[string]string{{"itemid": ids[key], "hostid": "1", "key_": key, "value_type": "3"}}
+		}
+		from := int64(params["time_from"].(float64))
+		values := make([]map[string]string, 0)
+		total := 0.0
+		for i := 0; i < 48; i++ {
+			total += increase(i)
+			value := increase(i)
+			if params["itemids"].([]interface{})[0] == "100" {
+				value = total
+			}
+			clock := strconv.FormatInt(from+int64(300*i+150), 10)
+			values = append(values, map[string]string{"clock": clock, "ns": "0", "value": strconv.FormatFloat(value, 'f', 0, 64)})
+		}
+		return values
+	})
+
+	configuration := zabbix.Configuration{}
+	configuration.Correlation = zabbix.CorrelationConfiguration{Window: 48 * 300, Interval: 300}
+	configuration.Items = []zabbix.ItemConfiguration{
+		{Filter: map[string][]string{"key_": {"app.requests.total"}}, Preprocessing: zabbix.PreprocessingConfiguration{Mode: "delta"}},
+		{Filter: map[string][]string{"key_": {"app.requests.rate"}}},
+	}
+	results := correlate(session, configuration, "app.requests.total")
+	assert.Len(t, results, 1)
+	assert.Equal(t, "app.requests.rate", results[0].Key)
+	assert.InDelta(t, 1, results[0].Correlation, 1e-9)
+	assert.Empty(t, itemPreprocessing)
+}
+
+func TestRankAndFormatCorrelations(t *testing.T) {
+	results := rankCorrelations([]correlationResult{
+		{Host: "web01", Key: "a", Correlation: 0.5},
+		{Host: "web01", Key: "b", Correlation: -0.9, Lag: 300, Samples: 280},
+		{Host: "web01", Key: "c", Correlation: 0.1},
+	}, 2)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "b", results[0].Key)
+	assert.Equal(t, "a", results[1].Key)
+
+	table, err := formatCorrelations(results, "text")
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(table)), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "RANK"))
+	assert.Equal(t, []string{"1", "web01", "b", "-0.900", "5m0s", "280"}, strings.Fields(lines[1]))
+
+	data, err := formatCorrelations(results, "json")
+	assert.Nil(t, err)
+	var decoded []correlationResult
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, results, decoded)
+
+	_, err = formatCorrelations(results, "table")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"sort"
+	"time"
+)
+
+/**
+ * Two sample Kolmogorov-Smirnov statistic: the largest distance between the empirical distribution functions
+ */
+func ksStatistic(a []float64, b []float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return math.NaN()
+	}
+	x := append([]float64(nil), a...)
+	y := append([]float64(nil), b...)
+	sort.Float64s(x)
+	sort.Float64s(y)
+
+	statistic := float64(0)
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		// step over all equal values before comparing, ties move both functions
+		value := math.Min(x[i], y[j])
+		for i < len(x) && x[i] == value {
+			i++
+		}
+		for j < len(y) && y[j] == value {
+			j++
+		}
+		distance := math.Abs(float64(i)/float64(len(x)) - float64(j)/float64(len(y)))
+		statistic = math.Max(statistic, distance)
+	}
+	return statistic
+}
+
+/**
+ * Asymptotic p-value of the KS statistic for samples of size n and m (Numerical Recipes, probks)
+ */
+func ksPValue(statistic float64, n int, m int) float64 {
+	if n == 0 || m == 0 || math.IsNaN(statistic) {
+		return math.NaN()
+	}
+	effective := math.Sqrt(float64(n) * float64(m) / float64(n+m))
+	lambda := (effective + 0.12 + 0.11/effective) * statistic
+	if lambda < 0.2 {
+		return 1
+	}
+	sum := float64(0)
+	sign := float64(1)
+	for k := 1; k <= 100; k++ {
+		term := sign * 2 * math.Exp(-2*float64(k*k)*lambda*lambda)
+		sum += term
+		if math.Abs(term) < 1e-10 {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(0, math.Min(1, sum))
+}
+
+/**
+ * KS statistic, p-value and quantile shifts (current - past) of two samples, keyed by output type
+ */
+func distributionValues(current []float64, past []float64) map[string]float64 {
+	statistic := ksStatistic(current, past)
+	return map[string]float64{
+		"ks":       statistic,
+		"pvalue":   ksPValue(statistic, len(current), len(past)),
+		"p50shift": percentile(current, 50) - percentile(past, 50),
+		"p95shift": percentile(current, 95) - percentile(past, 95),
+		"p99shift": percentile(current, 99) - percentile(past, 99),
+	}
+}
+
+/**
+ * Compare the values of the last window with the same time slot of the past weeks
+ */
+func processDistribution(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.Distribution
+	weeks := configuration.Weeks
+	if weeks == 0 {
+		weeks = 4
+	}
+	window := time.Duration(configuration.Window) * time.Second
+
+	now := time.Now()
+	current := make([]float64, 0)
+	for _, s := range loadSeries(session, item, now.Add(-window), now, "history") {
+		current = append(current, s.value)
+	}
+	past := make([]float64, 0)
+	for week := 1; week <= weeks; week++ {
+		end := now.Add(-time.Hour * 24 * 7 * time.Duration(week))
+		for _, s := range restrictSeries(item.ItemID, loadSeries(session, item, end.Add(-window), end, "history")) {
+			past = append(past, s.value)
+		}
+	}
+	if len(current) == 0 || len(past) == 0 {
+		Log.Warn("skipping item due to missing data", "item", item.ItemID, "current", len(current), "past", len(past))
+		return
+	}
+
+	values := distributionValues(current, past)
+	Log.Info("distribution", "item", item.ItemID, "current", len(current), "past", len(past), "ks", values["ks"], "pvalue", values["pvalue"])
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, "ks", "p95shift", "p99shift")
+	emitOutputs(item, outputs, now, values)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestKSStatistic(t *testing.T) {
+	assert.Equal(t, 0.0, ksStatistic([]float64{1, 2, 3}, []float64{3, 2, 1}))
+	assert.Equal(t, 1.0, ksStatistic([]float64{1, 2, 3}, []float64{4, 5, 6}))
+	assert.InDelta(t, 0.5, ksStatistic([]float64{1, 2, 3, 4}, []float64{3, 4, 5, 6}), 1e-9)
+	assert.True(t, math.IsNaN(ksStatistic(nil, []float64{1})))
+}
+
+func TestKSPValue(t *testing.T) {
+	assert.Equal(t, 1.0, ksPValue(0, 100, 100))
+	assert.Less(t, ksPValue(0.5, 100, 100), 0.001)
+	// critical value for alpha 0.05 is about 1.36 * sqrt(2/n)
+	assert.InDelta(t, 0.05, ksPValue(1.36*math.Sqrt(2.0/1000), 1000, 1000), 0.01)
+}
+
+func TestDistributionValues(t *testing.T) {
+	past := make([]float64, 0)
+	current := make([]float64, 0)
+	for i := 0; i < 100; i++ {
+		past = append(past, 10)
+		current = append(current, 10)
+	}
+	// same median, fatter tail
+	for i := 90; i < 100; i++ {
+		current[i] = 50
+	}
+	values := distributionValues(current, past)
+	assert.Equal(t, 0.0, values["p50shift"])
+	assert.Equal(t, 40.0, values["p95shift"])
+	assert.Equal(t, 40.0, values["p99shift"])
+	assert.InDelta(t, 0.1, values["ks"], 1e-9)
+}
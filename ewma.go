@@ -0,0 +1,116 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"time"
+)
+
+/**
+ * Exponentially weighted mean and variance of an item. Persisted between runs
+ */
+type ewmaState struct {
+	Clock    int64   `json:"clock"` // time of the last sample seen
+	Nano     int64   `json:"nano"`
+	Count    int64   `json:"count"`
+	Smoothed float64 `json:"smoothed"`
+	Variance float64 `json:"variance"`
+}
+
+/**
+ * Add a sample and return its score: the distance to the previous smoothed value in standard deviations
+ */
+func (s *ewmaState) update(value float64, alpha float64) float64 {
+	if s.Count == 0 {
+		s.Smoothed = value
+		s.Variance = 0
+		s.Count = 1
+		return 0
+	}
+	score := math.NaN()
+	difference := value - s.Smoothed
+	if s.Variance > 0 {
+		score = difference / math.Sqrt(s.Variance)
+	}
+	s.Smoothed += alpha * difference
+	s.Variance = (1 - alpha) * (s.Variance + alpha*difference*difference)
+	s.Count++
+	return score
+}
+
+/**
+ * Smoothed value, control limits and score of the last sample, keyed by output type
+ */
+func (s *ewmaState) values(score float64, limit float64) map[string]float64 {
+	deviation := math.Sqrt(s.Variance)
+	outOfControl := math.NaN()
+	if !math.IsNaN(score) {
+		outOfControl = 0
+		if math.Abs(score) > limit {
+			outOfControl = 1
+		}
+	}
+	return map[string]float64{
+		"smoothed":     s.Smoothed,
+		"lower":        s.Smoothed - limit*deviation,
+		"upper":        s.Smoothed + limit*deviation,
+		"score":        score,
+		"outofcontrol": outOfControl,
+	}
+}
+
+/**
+ * Samples after t. The history query starts at the full second of t
+ */
+func samplesAfter(series []sample, t time.Time) []sample {
+	result := make([]sample, 0, len(series))
+	for _, s := range series {
+		if s.time.After(t) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+/**
+ * Feed the samples since the last run into the EWMA of the item
+ */
+func processEWMA(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.EWMA
+	limit := configuration.Limit
+	if limit == 0 {
+		limit = 3
+	}
+	lookback := time.Duration(configuration.Lookback) * time.Second
+	if lookback == 0 {
+		lookback = time.Hour * 24
+	}
+
+	// configurations of the same item may use different smoothing
+	key := "ewma:" + item.ItemID + ":" + itemConfiguration.Postfix
+	state := ewmaState{}
+	now := time.Now()
+	from := now.Add(-lookback)
+	if getState(key, &state) {
+		from = time.Unix(state.Clock, state.Nano)
+	}
+
+	series := samplesAfter(loadSeries(session, item, from, now, "history"), from)
+	if len(series) == 0 {
+		Log.Warn("no new samples for ewma", "item", item.ItemID, "since", from.Format("Mon 01-02 15:04:05"))
+		return
+	}
+
+	score := math.NaN()
+	for _, s := range series {
+		score = state.update(s.value, configuration.Alpha)
+	}
+	latest := series[len(series)-1].time
+	state.Clock = latest.Unix()
+	state.Nano = int64(latest.Nanosecond())
+	putState(key, state)
+
+	Log.Info("ewma", "item", item.ItemID, "samples", len(series), "smoothed", state.Smoothed, "score", score)
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, "smoothed", "outofcontrol")
+	emitOutputs(item, outputs, latest, state.values(score, limit))
+}
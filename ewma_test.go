@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEWMAFlagsOutlier(t *testing.T) {
+	state := ewmaState{}
+	for i := 0; i < 50; i++ {
+		state.update(10+float64(i%2), 0.2)
+	}
+	values := state.values(state.update(10.5, 0.2), 3)
+	assert.Equal(t, 0.0, values["outofcontrol"])
+	assert.True(t, values["lower"] < values["smoothed"])
+	assert.True(t, values["upper"] > values["smoothed"])
+
+	values = state.values(state.update(20, 0.2), 3)
+	assert.Equal(t, 1.0, values["outofcontrol"])
+	assert.True(t, values["score"] > 3)
+}
+
+func TestEWMAFirstSample(t *testing.T) {
+	state := ewmaState{}
+	assert.Equal(t, 0.0, state.update(5, 0.5))
+	assert.True(t, math.IsNaN(state.update(6, 0.5)))
+	assert.Equal(t, 5.5, state.Smoothed)
+	assert.Equal(t, 0.25, state.Variance)
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	putState("ewma:1", ewmaState{Clock: 10, Count: 2, Smoothed: 1.5})
+	state := ewmaState{}
+	assert.True(t, getState("ewma:1", &state))
+	assert.Equal(t, int64(10), state.Clock)
+	assert.Equal(t, 1.5, state.Smoothed)
+	assert.False(t, getState("ewma:2", &state))
+}
+
+func TestSamplesAfter(t *testing.T) {
+	last := time.Unix(100, 500)
+	series := []sample{
+		{time: time.Unix(100, 0), value: 1},
+		{time: time.Unix(100, 500), value: 2},
+		{time: time.Unix(100, 600), value: 3},
+		{time: time.Unix(101, 0), value: 4},
+	}
+	assert.Equal(t, series[2:], samplesAfter(series, last))
+}
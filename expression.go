@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/**
+ * Parsed arithmetic expression
+ */
+type expression interface {
+	evaluate(variables map[string]float64) (float64, error)
+}
+
+type number float64
+
+type variable string
+
+type unary struct {
+	operator rune
+	operand  expression
+}
+
+type binary struct {
+	operator    rune
+	left, right expression
+}
+
+type call struct {
+	function  string
+	arguments []expression
+}
+
+func (n number) evaluate(variables map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (v variable) evaluate(variables map[string]float64) (float64, error) {
+	value, found := variables[string(v)]
+	if !found {
+		return math.NaN(), fmt.Errorf("unknown variable %s", string(v))
+	}
+	return value, nil
+}
+
+func (u unary) evaluate(variables map[string]float64) (float64, error) {
+	value, err := u.operand.evaluate(variables)
+	if u.operator == '-' {
+		value = -value
+	}
+	return value, err
+}
+
+func (b binary) evaluate(variables map[string]float64) (float64, error) {
+	left, err := b.left.evaluate(variables)
+	if err != nil {
+		return math.NaN(), err
+	}
+	right, err := b.right.evaluate(variables)
+	if err != nil {
+		return math.NaN(), err
+	}
+	switch b.operator {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/':
+		return left / right, nil
+	case '^':
+		return math.Pow(left, right), nil
+	}
+	return math.NaN(), fmt.Errorf("unknown operator %c", b.operator)
+}
+
+func (c call) evaluate(variables map[string]float64) (float64, error) {
+	arguments := make([]float64, len(c.arguments))
+	for i, argument := range c.arguments {
+		value, err := argument.evaluate(variables)
+		if err != nil {
+			return math.NaN(), err
+		}
+		arguments[i] = value
+	}
+
+	count := len(arguments)
+	switch {
+	case c.function == "abs" && count == 1:
+		return math.Abs(arguments[0]), nil
+	case c.function == "sqrt" && count == 1:
+		return math.Sqrt(arguments[0]), nil
+	case c.function == "log" && count == 1:
+		return math.Log(arguments[0]), nil
+	case c.function == "log" && count == 2:
+		return math.Log(arguments[0]) / math.Log(arguments[1]), nil
+	case c.function == "min" && count > 0:
+		result := arguments[0]
+		for _, value := range arguments[1:] {
+			result = math.Min(result, value)
+		}
+		return result, nil
+	case c.function == "max" && count > 0:
+		result := arguments[0]
+		for _, value := range arguments[1:] {
+			result = math.Max(result, value)
+		}
+		return result, nil
+	case c.function == "clamp" && count == 3:
+		return math.Max(arguments[1], math.Min(arguments[2], arguments[0])), nil
+	}
+	return math.NaN(), fmt.Errorf("unknown function %s with %d arguments", c.function, count)
+}
+
+/**
+ * Parse an expression with + - * / ^, parentheses, numbers, variables and the
+ * functions abs, sqrt, log, min, max and clamp
+ */
+func parseExpression(text string) (expression, error) {
+	p := parser{text: []rune(text)}
+	result, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.position < len(p.text) {
+		return nil, fmt.Errorf("unexpected '%c' at position %d", p.text[p.position], p.position)
+	}
+	return result, nil
+}
+
+type parser struct {
+	text     []rune
+	position int
+}
+
+func (p *parser) skipSpace() {
+	for p.position < len(p.text) && unicode.IsSpace(p.text[p.position]) {
+		p.position++
+	}
+}
+
+// next non space character without consuming it. 0 at the end
+func (p *parser) peek() rune {
+	p.skipSpace()
+	if p.position < len(p.text) {
+		return p.text[p.position]
+	}
+	return 0
+}
+
+func (p *parser) sum() (expression, error) {
+	left, err := p.product()
+	for err == nil && (p.peek() == '+' || p.peek() == '-') {
+		operator := p.text[p.position]
+		p.position++
+		var right expression
+		right, err = p.product()
+		left = binary{operator: operator, left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *parser) product() (expression, error) {
+	left, err := p.power()
+	for err == nil && (p.peek() == '*' || p.peek() == '/') {
+		operator := p.text[p.position]
+		p.position++
+		var right expression
+		right, err = p.power()
+		left = binary{operator: operator, left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *parser) power() (expression, error) {
+	base, err := p.unary()
+	if err == nil && p.peek() == '^' {
+		p.position++
+		var exponent expression
+		// right associative
+		exponent, err = p.power()
+		return binary{operator: '^', left: base, right: exponent}, err
+	}
+	return base, err
+}
+
+func (p *parser) unary() (expression, error) {
+	if p.peek() == '-' || p.peek() == '+' {
+		operator := p.text[p.position]
+		p.position++
+		operand, err := p.unary()
+		return unary{operator: operator, operand: operand}, err
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (expression, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.position++
+		inner, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ')' at position %d", p.position)
+		}
+		p.position++
+		return inner, nil
+	case unicode.IsDigit(c) || c == '.':
+		start := p.position
+		for p.position < len(p.text) && (unicode.IsDigit(p.text[p.position]) || strings.ContainsRune(".eE", p.text[p.position]) ||
+			(strings.ContainsRune("+-", p.text[p.position]) && strings.ContainsRune("eE", p.text[p.position-1]))) {
+			p.position++
+		}
+		value, err := strconv.ParseFloat(string(p.text[start:p.position]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number at position %d: %v", start, err)
+		}
+		return number(value), nil
+	case unicode.IsLetter(c) || c == '_':
+		start := p.position
+		for p.position < len(p.text) && (unicode.IsLetter(p.text[p.position]) || unicode.IsDigit(p.text[p.position]) || p.text[p.position] == '_') {
+			p.position++
+		}
+		name := string(p.text[start:p.position])
+		if p.peek() != '(' {
+			return variable(name), nil
+		}
+		p.position++
+		arguments := make([]expression, 0)
+		for p.peek() != ')' {
+			argument, err := p.sum()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+			if p.peek() == ',' {
+				p.position++
+			} else if p.peek() != ')' {
+				return nil, fmt.Errorf("expected ',' or ')' at position %d", p.position)
+			}
+		}
+		p.position++
+		return call{function: strings.ToLower(name), arguments: arguments}, nil
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected '%c' at position %d", c, p.position)
+}
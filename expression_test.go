@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func evaluate(t *testing.T, text string, variables map[string]float64) float64 {
+	parsed, err := parseExpression(text)
+	assert.Nil(t, err, text)
+	value, err := parsed.evaluate(variables)
+	assert.Nil(t, err, text)
+	return value
+}
+
+func TestExpressionArithmetic(t *testing.T) {
+	variables := map[string]float64{"a": 3, "b": 1}
+	assert.Equal(t, 75.0, evaluate(t, "100 * a / (a + b)", variables))
+	assert.Equal(t, 7.0, evaluate(t, "1 + 2 * 3", nil))
+	assert.Equal(t, -1.0, evaluate(t, "2 - 3", nil))
+	assert.Equal(t, 512.0, evaluate(t, "2 ^ 3 ^ 2", nil))
+	assert.Equal(t, -4.0, evaluate(t, "-2 * 2", nil))
+	assert.Equal(t, 1500.0, evaluate(t, "1.5e3", nil))
+}
+
+func TestExpressionFunctions(t *testing.T) {
+	variables := map[string]float64{"x": -5}
+	assert.Equal(t, 5.0, evaluate(t, "abs(x)", variables))
+	assert.Equal(t, -5.0, evaluate(t, "min(x, 0, 3)", variables))
+	assert.Equal(t, 3.0, evaluate(t, "max(x, 0, 3)", variables))
+	assert.Equal(t, 0.0, evaluate(t, "clamp(x, 0, 100)", variables))
+	assert.Equal(t, 2.0, evaluate(t, "log(100, 10)", nil))
+	assert.Equal(t, 1.0, evaluate(t, "log(2.718281828459045)", nil))
+	assert.True(t, math.IsInf(evaluate(t, "1 / 0", nil), 1))
+}
+
+func TestExpressionErrors(t *testing.T) {
+	for _, text := range []string{"1 +", "(1 + 2", "1 2", "max(1 2)", "$", ""} {
+		_, err := parseExpression(text)
+		assert.NotNil(t, err, text)
+	}
+
+	parsed, err := parseExpression("a + foo(1)")
+	assert.Nil(t, err)
+	_, err = parsed.evaluate(map[string]float64{"a": 1})
+	assert.NotNil(t, err)
+	_, err = parsed.evaluate(nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"strconv"
+	"time"
+)
+
+// reported as time left when the threshold is never reached, same as the ZABBIX timeleft() function
+const neverReached = 999999999999.9999
+
+/**
+ * Least squares fit y = intercept + slope * x
+ */
+func linearRegression(x []float64, y []float64) (slope float64, intercept float64) {
+	mx := mean(x)
+	my := mean(y)
+	var sxy, sxx float64
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+	}
+	if sxx == 0 {
+		return 0, my
+	}
+	slope = sxy / sxx
+	return slope, my - slope*mx
+}
+
+/**
+ * Theil-Sen estimator: median of the pairwise slopes. Robust against outliers
+ */
+func theilSen(x []float64, y []float64) (slope float64, intercept float64) {
+	slopes := make([]float64, 0, len(x)*(len(x)-1)/2)
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			if x[j] != x[i] {
+				slopes = append(slopes, (y[j]-y[i])/(x[j]-x[i]))
+			}
+		}
+	}
+	if len(slopes) == 0 {
+		return 0, median(y)
+	}
+	slope = median(slopes)
+	residuals := make([]float64, len(x))
+	for i := range x {
+		residuals[i] = y[i] - slope*x[i]
+	}
+	return slope, median(residuals)
+}
+
+func median(values []float64) float64 {
+	return percentile(values, 50)
+}
+
+/**
+ * Seconds from the last x until the fitted line reaches the threshold, keyed by output type
+ */
+func forecastValues(x []float64, y []float64, method string, threshold float64) map[string]float64 {
+	var slope, intercept float64
+	if method == "theilsen" {
+		slope, intercept = theilSen(x, y)
+	} else {
+		slope, intercept = linearRegression(x, y)
+	}
+
+	last := x[len(x)-1]
+	current := intercept + slope*last
+	timeleft := float64(neverReached)
+	if current >= threshold {
+		timeleft = 0
+	} else if slope > 0 {
+		timeleft = (threshold - current) / slope
+	}
+	return map[string]float64{"timeleft": timeleft, "slope": slope, "threshold": threshold}
+}
+
+/**
+ * Resolve the configured threshold for the host of the item. The threshold item key is a key template
+ * of the forecast item, e.g. vfs.fs.size[{param1},total]
+ */
+func forecastThreshold(session zabbix.Session, item zabbix.ItemResponseElement, configuration zabbix.ThresholdConfiguration) (float64, bool) {
+	threshold := configuration.Value
+	if configuration.Item != "" {
+		parsed, err := zabbix.ParseKey(item.Key)
+		if err != nil {
+			Log.Warn("cannot parse item key for the threshold item", "key", item.Key, "error", err)
+			return 0, false
+		}
+		key, err := parsed.Derive(configuration.Item, "")
+		if err != nil {
+			Log.Warn("cannot resolve threshold item", "key", item.Key, "template", configuration.Item, "error", err)
+			return 0, false
+		}
+		query := session.NewItemQuery([]string{item.HostID}, map[string][]string{"key_": {key}}, nil)
+		siblings := query.Query()
+		if len(siblings) == 0 {
+			Log.Warn("threshold item not found", "host", hosts[item.HostID], "key", key)
+			return 0, false
+		}
+		value, found := lastValue(session, siblings[0])
+		if !found {
+			Log.Warn("threshold item without value", "host", hosts[item.HostID], "key", key)
+			return 0, false
+		}
+		threshold = value
+	} else if configuration.Macro != "" {
+		text, found := session.HostMacros(item.HostID)[configuration.Macro]
+		if !found {
+			Log.Warn("threshold macro not found", "host", hosts[item.HostID], "macro", configuration.Macro)
+			return 0, false
+		}
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			Log.Warn("threshold macro is not numeric", "macro", configuration.Macro, "value", text)
+			return 0, false
+		}
+		threshold = value
+	}
+	if configuration.Percent != 0 {
+		threshold = threshold * configuration.Percent / 100
+	}
+	return threshold, true
+}
+
+/**
+ * Most recent numeric value of an item
+ */
+func lastValue(session zabbix.Session, item zabbix.ItemResponseElement) (float64, bool) {
+	query := session.NewHistoryQuery()
+	query.ValueType = item.ValueType
+	query.Items = []string{item.ItemID}
+	query.Limit = 1
+	if _, found := itemPreprocessing[item.ItemID]; found {
+		// rate and delta need the previous value
+		query.Limit = 2
+	}
+	values := preprocessHistory(item.ItemID, query.Query())
+	if len(values) == 0 {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(values[0].Value, 64)
+	return value, err == nil
+}
+
+/**
+ * Extrapolate the item history and emit the time left until the threshold is reached
+ */
+func processForecast(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.Forecast
+	interval := time.Duration(configuration.Interval) * time.Second
+	if interval == 0 {
+		interval = time.Hour
+	}
+
+	threshold, found := forecastThreshold(session, item, configuration.Threshold)
+	if !found {
+		Log.Warn("skipping item without threshold", "item", item)
+		return
+	}
+
+	now := time.Now()
+	series := loadSeries(session, item, now.Add(-time.Duration(configuration.Lookback)*time.Second), now, configuration.Source)
+	if len(series) < 2 {
+		Log.Warn("skipping item due to missing data", "item", item)
+		return
+	}
+	sampler := newResampler(itemConfiguration.Resample, interval)
+	// the last bucket may be incomplete, the results are emitted at the time of the latest sample
+	latest := sampler.ceil(series[len(series)-1].time)
+	count := int(time.Duration(configuration.Lookback) * time.Second / interval)
+	buckets := sampler.resample(series, latest, count)
+
+	x := make([]float64, 0, len(buckets))
+	y := make([]float64, 0, len(buckets))
+	for i, value := range buckets {
+		if !math.IsNaN(value) {
+			x = append(x, float64(latest.Add(-interval*time.Duration(len(buckets)-1-i)).Unix()))
+			y = append(y, value)
+		}
+	}
+	if len(x) < 2 {
+		Log.Warn("not enough history for forecast", "item", item.ItemID, "buckets", len(x))
+		return
+	}
+
+	values := forecastValues(x, y, configuration.Method, threshold)
+	Log.Info("forecast", "item", item.ItemID, "threshold", threshold, "slope", values["slope"], "timeleft", values["timeleft"])
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, "timeleft", "slope")
+	emitOutputs(item, outputs, series[len(series)-1].time, values)
+}
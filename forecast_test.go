@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLinearRegression(t *testing.T) {
+	slope, intercept := linearRegression([]float64{0, 1, 2, 3}, []float64{1, 3, 5, 7})
+	assert.InDelta(t, 2, slope, 1e-9)
+	assert.InDelta(t, 1, intercept, 1e-9)
+}
+
+func TestTheilSenIgnoresOutlier(t *testing.T) {
+	x := []float64{0, 1, 2, 3, 4, 5, 6}
+	y := []float64{0, 1, 2, 100, 4, 5, 6}
+	slope, intercept := theilSen(x, y)
+	assert.InDelta(t, 1, slope, 1e-9)
+	assert.InDelta(t, 0, intercept, 1e-9)
+
+	slope, _ = linearRegression(x, y)
+	assert.True(t, slope < 1)
+}
+
+func TestForecastValues(t *testing.T) {
+	x := []float64{0, 10, 20}
+	y := []float64{10, 20, 30}
+	values := forecastValues(x, y, "linear", 100)
+	assert.InDelta(t, 1, values["slope"], 1e-9)
+	assert.InDelta(t, 70, values["timeleft"], 1e-9)
+
+	values = forecastValues(x, []float64{30, 20, 10}, "theilsen", 100)
+	assert.Equal(t, float64(neverReached), values["timeleft"])
+
+	values = forecastValues(x, y, "linear", 25)
+	assert.Equal(t, 0.0, values["timeleft"])
+}
+
+func TestForecastThresholdFromSiblingItem(t *testing.T) {
+	session := fakeAPI(t, func(method string, params map[string]interface{}) interface{} {
+		switch method {
+		case "item.get":
+			filter := params["filter"].(map[string]interface{})
+			if filter["key_"].([]interface{})[0] == "vfs.fs.size[/var,total]" {
+				return []map[string]string{{"itemid": "20", "hostid": "10", "value_type": "3", "key_": "vfs.fs.size[/var,total]"}}
+			}
+		case "history.get":
+			if params["itemids"].([]interface{})[0] == "20" {
+				return []map[string]string{{"itemid": "20", "clock": "100", "value": "1000"}}
+			}
+		}
+		return []string{}
+	})
+	item := zabbix.ItemResponseElement{ItemID: "2", HostID: "10", Key: "vfs.fs.size[/var,used]"}
+
+	threshold, found := forecastThreshold(session, item, zabbix.ThresholdConfiguration{Item: "vfs.fs.size[{param1},total]", Percent: 90})
+	assert.True(t, found)
+	assert.Equal(t, 900.0, threshold)
+
+	_, found = forecastThreshold(session, item, zabbix.ThresholdConfiguration{Item: "vfs.fs.size[{param1},free]"})
+	assert.False(t, found)
+	_, found = forecastThreshold(session, item, zabbix.ThresholdConfiguration{Item: "vfs.fs.size[{param3},total]"})
+	assert.False(t, found)
+}
+
+func TestForecastThresholdFromMacro(t *testing.T) {
+	session := fakeAPI(t, func(method string, params map[string]interface{}) interface{} {
+		switch method {
+		case "host.get":
+			return []interface{}{map[string]interface{}{"hostid": "11", "parentTemplates": []map[string]string{{"templateid": "50"}}}}
+		case "usermacro.get":
+			if params["globalmacro"] == true {
+				return []map[string]string{{"macro": "{$DISK.LIMIT}", "value": "10"}, {"macro": "{$GLOBAL.LIMIT}", "value": "30"}}
+			}
+			if params["hostids"].([]interface{})[0] == "50" {
+				return []map[string]string{{"macro": "{$DISK.LIMIT}", "value": "200"}}
+			}
+		}
+		return []string{}
+	})
+	item := zabbix.ItemResponseElement{ItemID: "3", HostID: "11", Key: "vfs.fs.size[/,used]"}
+
+	// the template overrides the global macro
+	threshold, found := forecastThreshold(session, item, zabbix.ThresholdConfiguration{Macro: "{$DISK.LIMIT}"})
+	assert.True(t, found)
+	assert.Equal(t, 200.0, threshold)
+	threshold, found = forecastThreshold(session, item, zabbix.ThresholdConfiguration{Macro: "{$GLOBAL.LIMIT}", Percent: 50})
+	assert.True(t, found)
+	assert.Equal(t, 15.0, threshold)
+	_, found = forecastThreshold(session, item, zabbix.ThresholdConfiguration{Macro: "{$MISSING}"})
+	assert.False(t, found)
+}
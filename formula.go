@@ -0,0 +1,78 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"time"
+)
+
+/**
+ * Value of a formula input on a host
+ */
+func formulaInput(session zabbix.Session, hostID string, input zabbix.FormulaInputConfiguration) (float64, bool) {
+	if input.Output != "" {
+		value, found := emitted[hosts[hostID]][input.Output]
+		return value.value, found
+	}
+
+	query := session.NewItemQuery([]string{hostID}, input.Filter, input.Search)
+	items := query.Query()
+	if len(items) == 0 {
+		return 0, false
+	}
+	if len(items) > 1 {
+		Log.Warn("formula input matches several items, using the first", "host", hosts[hostID], "key", items[0].Key, "count", len(items))
+	}
+
+	if input.PastWeeks.Weeks > 0 {
+		halfWindow, found := pastWeeksWindow(session, items[0], input.PastWeeks)
+		if !found {
+			return 0, false
+		}
+		comparison := compareWeeks(session, items[0], input.PastWeeks.Weeks, halfWindow, input.PastWeeks.Exclude)
+		baseline := average(comparison.samples)
+		return baseline, !math.IsNaN(baseline)
+	}
+	return lastValue(session, items[0])
+}
+
+/**
+ * Evaluate the formulas for every host and write the results
+ */
+func processFormulas(session zabbix.Session, formulas []zabbix.FormulaConfiguration) {
+	for _, formula := range formulas {
+		parsed, err := parseExpression(formula.Expression)
+		if err != nil {
+			Log.Error("invalid formula", "key", formula.Key, "expression", formula.Expression, "error", err)
+			continue
+		}
+
+		for hostID, hostname := range hosts {
+			variables := make(map[string]float64)
+			complete := true
+			for name, input := range formula.Inputs {
+				value, found := formulaInput(session, hostID, input)
+				if !found {
+					Log.Debug("formula input without value", "host", hostname, "key", formula.Key, "input", name)
+					complete = false
+					break
+				}
+				variables[name] = value
+			}
+			if !complete {
+				continue
+			}
+
+			value, err := parsed.evaluate(variables)
+			if err != nil {
+				Log.Warn("formula evaluation failed", "host", hostname, "key", formula.Key, "error", err)
+				continue
+			}
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				Log.Warn("formula without value", "host", hostname, "key", formula.Key, "variables", variables)
+				continue
+			}
+			addSenderLine(hostname, formula.Key, time.Now(), value)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// web.requests[error] on host 1 with the latest value 5
+func formulaAPI(t *testing.T) zabbix.Session {
+	return fakeAPI(t, func(method string, params map[string]interface{}) interface{} {
+		switch method {
+		case "item.get":
+			filter := params["filter"].(map[string]interface{})
+			if params["hostids"].([]interface{})[0] == "1" && filter["key_"].([]interface{})[0] == "web.requests[error]" {
+				return []map[string]string{{"itemid": "30", "hostid": "1", "value_type": "3", "key_": "web.requests[error]"}}
+			}
+		case "history.get":
+			return []map[string]string{{"itemid": "30", "clock": "100", "value": "5"}}
+		}
+		return []string{}
+	})
+}
+
+func TestFormulaInput(t *testing.T) {
+	defer func(original map[string]string) { hosts = original }(hosts)
+	hosts = map[string]string{"1": "web", "2": "db"}
+	defer func() { emitted = make(map[string]map[string]sample) }()
+	emitted["web"] = map[string]sample{"web.requests.3wd": {time: time.Now(), value: 12}}
+	session := formulaAPI(t)
+
+	value, found := formulaInput(session, "1", zabbix.FormulaInputConfiguration{Output: "web.requests.3wd"})
+	assert.True(t, found)
+	assert.Equal(t, 12.0, value)
+	_, found = formulaInput(session, "2", zabbix.FormulaInputConfiguration{Output: "web.requests.3wd"})
+	assert.False(t, found)
+
+	errors := zabbix.FormulaInputConfiguration{Filter: map[string][]string{"key_": {"web.requests[error]"}}}
+	value, found = formulaInput(session, "1", errors)
+	assert.True(t, found)
+	assert.Equal(t, 5.0, value)
+	_, found = formulaInput(session, "2", errors)
+	assert.False(t, found)
+}
+
+func TestProcessFormulas(t *testing.T) {
+	defer func(original map[string]string) { hosts = original }(hosts)
+	hosts = map[string]string{"1": "web", "2": "db"}
+	defer func() {
+		emitted = make(map[string]map[string]sample)
+		zabbixSenderBytes.Reset()
+	}()
+	emitted["web"] = map[string]sample{"web.requests.total": {time: time.Now(), value: 20}}
+	emitted["db"] = map[string]sample{"web.requests.total": {time: time.Now(), value: 0}}
+
+	inputs := map[string]zabbix.FormulaInputConfiguration{
+		"errors": {Filter: map[string][]string{"key_": {"web.requests[error]"}}},
+		"total":  {Output: "web.requests.total"},
+	}
+	processFormulas(formulaAPI(t), []zabbix.FormulaConfiguration{
+		{Key: "http.error.ratio", Expression: "100 * errors / total", Inputs: inputs},
+		{Key: "invalid", Expression: "100 * (errors", Inputs: inputs},
+	})
+
+	assert.Equal(t, 25.0, emitted["web"]["http.error.ratio"].value)
+	// no error item on db
+	assert.NotContains(t, emitted["db"], "http.error.ratio")
+	assert.NotContains(t, emitted["web"], "invalid")
+	assert.Contains(t, zabbixSenderBytes.String(), `"web" http.error.ratio`)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"sort"
+	"time"
+)
+
+// Host ID to item key to the largest absolute normalized deviation (pastweeks deviations, emitted zscore and score outputs) of this run
+var itemScores = make(map[string]map[string]float64)
+
+// Output types counted for the health score when they are emitted without neutral substitution
+var scoreTypes = map[string]bool{"zscore": true, "score": true}
+
+/**
+ * Remember the normalized deviation of the item for the health score
+ */
+func recordScore(item zabbix.ItemResponseElement, score float64) {
+	if math.IsNaN(score) || math.IsInf(score, 0) {
+		return
+	}
+	if itemScores[item.HostID] == nil {
+		itemScores[item.HostID] = make(map[string]float64)
+	}
+	if previous, found := itemScores[item.HostID][item.Key]; !found || math.Abs(score) > previous {
+		itemScores[item.HostID][item.Key] = math.Abs(score)
+	}
+}
+
+/**
+ * Remember the past weeks deviation of the item in standard deviations of the past weeks, whichever outputs are
+ * configured. The default absolute output has the unit of the item and cannot be compared across items.
+ * Items replaced by their neutral value are not counted
+ */
+func recordDeviationScore(item zabbix.ItemResponseElement, values map[string]float64) {
+	if _, neutral := neutralValue(item.ItemID, "zscore"); neutral {
+		return
+	}
+	recordScore(item, values["zscore"])
+}
+
+/**
+ * Weight and cap of the first weight configuration matching the key. Weight 1 and the default cap otherwise
+ */
+func healthWeight(configuration zabbix.HealthConfiguration, key string) (float64, float64) {
+	limit := configuration.Cap
+	if limit == 0 {
+		limit = 5
+	}
+	for _, weight := range configuration.Weights {
+		if zabbix.MatchWildcard(weight.Key, key) {
+			if weight.Cap > 0 {
+				limit = weight.Cap
+			}
+			return weight.Weight, limit
+		}
+	}
+	return 1, limit
+}
+
+/**
+ * Health from 100 (no deviation) to 0 (all items at their cap): 100 * (1 - weighted mean of min(score, cap) / cap).
+ * Returns the key with the largest weighted contribution as well
+ */
+func healthScore(scores map[string]float64, configuration zabbix.HealthConfiguration) (float64, string) {
+	keys := make([]string, 0, len(scores))
+	for key := range scores {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var total, weights, worst float64
+	contributor := ""
+	for _, key := range keys {
+		weight, limit := healthWeight(configuration, key)
+		if weight <= 0 {
+			continue
+		}
+		contribution := weight * math.Min(scores[key], limit) / limit
+		total += contribution
+		weights += weight
+		if contributor == "" || contribution > worst {
+			worst, contributor = contribution, key
+		}
+	}
+	if weights == 0 {
+		return math.NaN(), ""
+	}
+	return 100 * (1 - total/weights), contributor
+}
+
+/**
+ * Emit the health score and the top contributor of every host with scored items
+ */
+func processHealth(configuration zabbix.HealthConfiguration) {
+	now := time.Now()
+	for hostID, scores := range itemScores {
+		score, contributor := healthScore(scores, configuration)
+		if math.IsNaN(score) {
+			continue
+		}
+		Log.Info("host health", "host", hosts[hostID], "score", score, "items", len(scores), "contributor", contributor)
+		addSenderLine(hosts[hostID], configuration.Key, now, score)
+		if configuration.Contributor != "" {
+			addSenderText(hosts[hostID], configuration.Contributor, now, contributor)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRecordScore(t *testing.T) {
+	defer func() { itemScores = make(map[string]map[string]float64) }()
+	item := zabbix.ItemResponseElement{HostID: "1", Key: "system.cpu.load"}
+	recordScore(item, 2)
+	recordScore(item, -3)
+	recordScore(item, 1)
+	recordScore(item, math.NaN())
+	assert.Equal(t, map[string]float64{"system.cpu.load": 3}, itemScores["1"])
+}
+
+func TestRecordEmittedScores(t *testing.T) {
+	defer func() {
+		itemScores = make(map[string]map[string]float64)
+		emitted = make(map[string]map[string]sample)
+		zabbixSenderBytes.Reset()
+		delete(neutralValues, "2")
+	}()
+	outputs := []zabbix.OutputConfiguration{{Type: "zscore", Postfix: ".3wd"}}
+	values := map[string]float64{"zscore": -4, "absolute": 2}
+	emitOutputs(zabbix.ItemResponseElement{ItemID: "1", HostID: "1", Key: "a"}, outputs, time.Now(), values)
+
+	// neutralised outside the schedule
+	neutralValues["2"] = 0
+	emitOutputs(zabbix.ItemResponseElement{ItemID: "2", HostID: "1", Key: "b"}, outputs, time.Now(), values)
+
+	// zscore not emitted
+	absolute := []zabbix.OutputConfiguration{{Type: "absolute", Postfix: ".3wd"}}
+	emitOutputs(zabbix.ItemResponseElement{ItemID: "3", HostID: "1", Key: "c"}, absolute, time.Now(), values)
+
+	assert.Equal(t, map[string]float64{"a": 4}, itemScores["1"])
+}
+
+func TestRecordDeviationScore(t *testing.T) {
+	defer func() {
+		itemScores = make(map[string]map[string]float64)
+		delete(neutralValues, "2")
+	}()
+	// recorded without a zscore output
+	recordDeviationScore(zabbix.ItemResponseElement{ItemID: "1", HostID: "1", Key: "a"}, deviations(14, []float64{8, 10, 12}))
+	neutralValues["2"] = 0
+	recordDeviationScore(zabbix.ItemResponseElement{ItemID: "2", HostID: "1", Key: "b"}, deviations(14, []float64{8, 10, 12}))
+	// constant past weeks have no standard deviation
+	recordDeviationScore(zabbix.ItemResponseElement{ItemID: "3", HostID: "1", Key: "c"}, deviations(14, []float64{10, 10, 10}))
+
+	assert.Len(t, itemScores["1"], 1)
+	assert.InDelta(t, 4/standardDeviation([]float64{8, 10, 12}), itemScores["1"]["a"], 1e-9)
+}
+
+func TestHealthScore(t *testing.T) {
+	configuration := zabbix.HealthConfiguration{
+		Cap: 4,
+		Weights: []zabbix.HealthWeightConfiguration{
+			{Key: "system.cpu*", Weight: 3},
+			{Key: "log[*", Weight: 0},
+			{Key: "web.*", Weight: 1, Cap: 2},
+		},
+	}
+	score, contributor := healthScore(map[string]float64{"system.cpu.load": 0, "web.time": 0}, configuration)
+	assert.Equal(t, 100.0, score)
+
+	// cpu 2/4 * 3, web capped 1 * 1, log ignored
+	score, contributor = healthScore(map[string]float64{"system.cpu.load": 2, "web.time": 10, "log[app]": 50}, configuration)
+	assert.InDelta(t, 100*(1-2.5/4), score, 1e-9)
+	assert.Equal(t, "system.cpu.load", contributor)
+
+	score, _ = healthScore(map[string]float64{"other": 5}, zabbix.HealthConfiguration{})
+	assert.Equal(t, 0.0, score)
+
+	score, contributor = healthScore(map[string]float64{"log[app]": 5}, configuration)
+	assert.True(t, math.IsNaN(score))
+	assert.Equal(t, "", contributor)
+}
+
+func TestProcessHealth(t *testing.T) {
+	defer func(original map[string]string) { hosts = original }(hosts)
+	hosts = map[string]string{"1": "web", "2": "db"}
+	defer func() {
+		itemScores = make(map[string]map[string]float64)
+		emitted = make(map[string]map[string]sample)
+		zabbixSenderBytes.Reset()
+	}()
+	zscore := []zabbix.OutputConfiguration{{Type: "zscore", Postfix: ".3wdz"}}
+	emitOutputs(zabbix.ItemResponseElement{ItemID: "1", HostID: "1", Key: "system.cpu.load"}, zscore, time.Now(), map[string]float64{"zscore": -6})
+	recordDeviationScore(zabbix.ItemResponseElement{ItemID: "2", HostID: "1", Key: "net.if.in"}, map[string]float64{"zscore": 1})
+	emitOutputs(zabbix.ItemResponseElement{ItemID: "3", HostID: "2", Key: "ignored.load"}, zscore, time.Now(), map[string]float64{"zscore": 3})
+
+	processHealth(zabbix.HealthConfiguration{Key: "health.score", Contributor: "health.worst", Cap: 4,
+		Weights: []zabbix.HealthWeightConfiguration{{Key: "ignored.*", Weight: 0}}})
+
+	// (min(6, 4) / 4 + 1 / 4) / 2 of the items deviate
+	assert.InDelta(t, 37.5, emitted["web"]["health.score"].value, 1e-9)
+	assert.Regexp(t, `"web" health.worst \d+ system.cpu.load\n`, zabbixSenderBytes.String())
+	// only items without weight
+	assert.NotContains(t, emitted["db"], "health.score")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"time"
+)
+
+/**
+ * Additive Holt-Winters (triple exponential smoothing)
+ */
+type holtWinters struct {
+	alpha  float64 // level
+	beta   float64 // trend
+	gamma  float64 // season
+	period int     // buckets per season
+}
+
+/**
+ * One step ahead forecasts for every bucket after the first season.
+ * forecasts[i] predicts series[period+i]. next is the forecast for the bucket following the series.
+ */
+func (h holtWinters) fit(series []float64) (forecasts []float64, next float64) {
+	m := h.period
+	level := mean(series[:m])
+	trend := (mean(series[m:2*m]) - level) / float64(m)
+	seasonal := make([]float64, m)
+	for i := 0; i < m; i++ {
+		seasonal[i] = series[i] - level
+	}
+
+	forecasts = make([]float64, 0, len(series)-m)
+	for t := m; t < len(series); t++ {
+		s := seasonal[t%m]
+		forecasts = append(forecasts, level+trend+s)
+
+		previous := level
+		level = h.alpha*(series[t]-s) + (1-h.alpha)*(level+trend)
+		trend = h.beta*(level-previous) + (1-h.beta)*trend
+		seasonal[t%m] = h.gamma*(series[t]-level) + (1-h.gamma)*s
+	}
+	return forecasts, level + trend + seasonal[len(series)%m]
+}
+
+/**
+ * Sum of squared one step ahead errors
+ */
+func (h holtWinters) sse(series []float64) float64 {
+	forecasts, _ := h.fit(series)
+	sum := float64(0)
+	for i, forecast := range forecasts {
+		e := series[h.period+i] - forecast
+		sum += e * e
+	}
+	return sum
+}
+
+/**
+ * Find smoothing parameters with the smallest squared error on a coarse grid
+ */
+func estimateHoltWinters(series []float64, period int) holtWinters {
+	grid := []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.7, 0.9}
+	best := holtWinters{alpha: 0.5, beta: 0.1, gamma: 0.1, period: period}
+	bestError := math.Inf(1)
+	for _, alpha := range grid {
+		for _, beta := range grid {
+			for _, gamma := range grid {
+				candidate := holtWinters{alpha: alpha, beta: beta, gamma: gamma, period: period}
+				if e := candidate.sse(series); e < bestError {
+					best = candidate
+					bestError = e
+				}
+			}
+		}
+	}
+	return best
+}
+
+/**
+ * Forecast, prediction interval and residual of the latest bucket, keyed by output type
+ */
+func holtWintersValues(series []float64, model holtWinters, deviations float64) map[string]float64 {
+	values := map[string]float64{"forecast": math.NaN(), "lower": math.NaN(), "upper": math.NaN(), "residual": math.NaN()}
+	if len(series) < 2*model.period+1 {
+		return values
+	}
+
+	forecasts, _ := model.fit(series)
+	last := len(forecasts) - 1
+	residuals := make([]float64, 0, last)
+	for i := 0; i < last; i++ {
+		residuals = append(residuals, series[model.period+i]-forecasts[i])
+	}
+	spread := deviations * math.Sqrt(meanSquare(residuals))
+
+	values["forecast"] = forecasts[last]
+	values["lower"] = forecasts[last] - spread
+	values["upper"] = forecasts[last] + spread
+	values["residual"] = series[len(series)-1] - forecasts[last]
+	return values
+}
+
+func meanSquare(values []float64) float64 {
+	sum := float64(0)
+	for _, value := range values {
+		sum += value * value
+	}
+	return sum / float64(len(values))
+}
+
+/**
+ * Fit Holt-Winters on the item history and evaluate the latest bucket
+ */
+func processHoltWinters(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.HoltWinters
+	season := time.Duration(configuration.Season) * time.Second
+	if season == 0 {
+		season = time.Hour * 24 * 7
+	}
+	interval := time.Duration(configuration.Interval) * time.Second
+	if interval == 0 {
+		interval = time.Hour
+	}
+	deviations := configuration.Deviations
+	if deviations == 0 {
+		deviations = 2
+	}
+
+	now := time.Now()
+	series := loadSeries(session, item, now.Add(-time.Duration(configuration.Lookback)*time.Second), now, configuration.Source)
+	if len(series) == 0 {
+		Log.Warn("skipping item due to missing data", "item", item)
+		return
+	}
+	sampler := newResampler(itemConfiguration.Resample, interval)
+	// the last bucket may be incomplete, the results are emitted at the time of the latest sample
+	latest := sampler.ceil(series[len(series)-1].time)
+	count := int(time.Duration(configuration.Lookback) * time.Second / interval)
+	buckets := skipMissing(sampler.resample(series, latest, count))
+
+	period := int(season / interval)
+	if period < 2 || len(buckets) < 2*period+1 {
+		Log.Warn("not enough history for holt-winters", "item", item.ItemID, "buckets", len(buckets), "period", period)
+		return
+	}
+
+	model := holtWinters{alpha: configuration.Alpha, beta: configuration.Beta, gamma: configuration.Gamma, period: period}
+	if model.alpha == 0 && model.beta == 0 && model.gamma == 0 {
+		model = estimateHoltWinters(buckets, period)
+	}
+	Log.Info("holt-winters model", "item", item.ItemID, "alpha", model.alpha, "beta", model.beta, "gamma", model.gamma)
+
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, "forecast", "lower", "upper", "residual")
+	emitOutputs(item, outputs, series[len(series)-1].time, holtWintersValues(buckets, model, deviations))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+// daily pattern of 24 buckets on a slowly rising level
+func seasonalSeries(days int) []float64 {
+	series := make([]float64, 0, days*24)
+	for i := 0; i < days*24; i++ {
+		series = append(series, 100+0.1*float64(i)+10*math.Sin(2*math.Pi*float64(i)/24))
+	}
+	return series
+}
+
+func TestHoltWintersFollowsTrendAndSeason(t *testing.T) {
+	series := seasonalSeries(10)
+	model := holtWinters{alpha: 0.3, beta: 0.1, gamma: 0.3, period: 24}
+	forecasts, next := model.fit(series)
+	assert.Equal(t, len(series)-24, len(forecasts))
+	last := len(series) - 1
+	assert.InDelta(t, series[last], forecasts[len(forecasts)-1], 1)
+	assert.InDelta(t, 100+0.1*float64(last+1)+10*math.Sin(2*math.Pi*float64(last+1)/24), next, 1)
+}
+
+func TestHoltWintersValues(t *testing.T) {
+	series := seasonalSeries(10)
+	series[len(series)-1] += 20
+	model := estimateHoltWinters(series[:len(series)-1], 24)
+	values := holtWintersValues(series, model, 2)
+	assert.InDelta(t, 20, values["residual"], 2)
+	assert.True(t, values["lower"] < values["forecast"])
+	assert.True(t, values["upper"] > values["forecast"])
+	assert.True(t, series[len(series)-1] > values["upper"])
+}
+
+func TestHoltWintersValuesNeedTwoSeasons(t *testing.T) {
+	values := holtWintersValues(seasonalSeries(1), holtWinters{alpha: 0.5, period: 24}, 2)
+	assert.True(t, math.IsNaN(values["forecast"]))
+}
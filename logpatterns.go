@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"regexp"
+	"time"
+)
+
+/**
+ * Compiled pattern of the log algorithm
+ */
+type logPattern struct {
+	name       string
+	regex      *regexp.Regexp
+	severities []int
+	eventIDs   []int
+}
+
+func compilePatterns(configurations []zabbix.LogPatternConfiguration) ([]logPattern, error) {
+	patterns := make([]logPattern, 0, len(configurations))
+	for _, configuration := range configurations {
+		regex, err := regexp.Compile(configuration.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %v", configuration.Name, err)
+		}
+		patterns = append(patterns, logPattern{
+			name: configuration.Name, regex: regex, severities: configuration.Severities, eventIDs: configuration.EventIDs,
+		})
+	}
+	return patterns, nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * True if the value matches the regular expression and the severity and event id filters
+ */
+func (p logPattern) matches(value zabbix.HistoryValue) bool {
+	if len(p.severities) > 0 && !containsInt(p.severities, value.Severity) {
+		return false
+	}
+	if len(p.eventIDs) > 0 && !containsInt(p.eventIDs, value.LogEventID) {
+		return false
+	}
+	return p.regex.MatchString(value.Value)
+}
+
+/**
+ * Number of matching values per pattern name
+ */
+func countMatches(values []zabbix.HistoryValue, patterns []logPattern) map[string]float64 {
+	counts := make(map[string]float64, len(patterns))
+	for _, pattern := range patterns {
+		counts[pattern.name] = 0
+		for _, value := range values {
+			if pattern.matches(value) {
+				counts[pattern.name]++
+			}
+		}
+	}
+	return counts
+}
+
+/**
+ * History values of any type in [from, to)
+ */
+func loadValues(session zabbix.Session, item zabbix.ItemResponseElement, from time.Time, to time.Time) []zabbix.HistoryValue {
+	query := session.NewHistoryQuery()
+	query.ValueType = item.ValueType
+	query.Items = []string{item.ItemID}
+	query.From = from.Unix()
+	query.To = to.Unix()
+	values := make([]zabbix.HistoryValue, 0)
+	for _, value := range query.Query() {
+		// time_till is inclusive
+		if value.Clock < to.Unix() {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+/**
+ * Count the pattern matches in the last complete bucket, optionally compared with the same bucket of the past weeks
+ */
+func processLog(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.Log
+	patterns, err := compilePatterns(configuration.Patterns)
+	if err != nil {
+		Log.Error("skipping item with invalid log pattern", "item", item.ItemID, "error", err)
+		return
+	}
+
+	interval := time.Duration(configuration.Interval) * time.Second
+	end := time.Now().Truncate(interval)
+	values := countMatches(loadValues(session, item, end.Add(-interval), end), patterns)
+
+	if configuration.Weeks > 0 {
+		baselines := make(map[string]float64, len(patterns))
+		for week := 1; week <= configuration.Weeks; week++ {
+			past := end.Add(-time.Hour * 24 * 7 * time.Duration(week))
+			for name, count := range countMatches(loadValues(session, item, past.Add(-interval), past), patterns) {
+				baselines[name] += count / float64(configuration.Weeks)
+			}
+		}
+		for name, baseline := range baselines {
+			values[name+".baseline"] = baseline
+			values[name+".change"] = values[name] - baseline
+		}
+	}
+
+	Log.Info("log patterns", "item", item.ItemID, "bucket", end.Format("Mon 01-02 15:04:05"), "counts", values)
+	names := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		names[i] = pattern.name
+	}
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, names...)
+	emitOutputs(item, outputs, end, values)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCountMatches(t *testing.T) {
+	patterns, err := compilePatterns([]zabbix.LogPatternConfiguration{
+		{Name: "errors", Regex: "(?i)error"},
+		{Name: "logins", Regex: "login", Severities: []int{2}, EventIDs: []int{4625}},
+		{Name: "all"},
+	})
+	assert.Nil(t, err)
+	values := []zabbix.HistoryValue{
+		{Value: "ERROR: disk full"},
+		{Value: "login failed", Severity: 2, LogEventID: 4625},
+		{Value: "login failed", Severity: 1, LogEventID: 4625},
+		{Value: "login succeeded", Severity: 2, LogEventID: 4624},
+	}
+	counts := countMatches(values, patterns)
+	assert.Equal(t, map[string]float64{"errors": 1, "logins": 1, "all": 4}, counts)
+	assert.Equal(t, map[string]float64{"errors": 0, "logins": 0, "all": 0}, countMatches(nil, patterns))
+
+	_, err = compilePatterns([]zabbix.LogPatternConfiguration{{Name: "broken", Regex: "(error"}})
+	assert.NotNil(t, err)
+}
+
+func TestLogHistoryValue(t *testing.T) {
+	var values []zabbix.HistoryValue
+	data := `[{"itemid":"1","clock":"1546851600","ns":"0","value":"login failed","timestamp":"0","source":"Security","severity":"7","logeventid":"4625"},
+		{"itemid":"2","clock":"1546851600","ns":"0","value":"1.5"}]`
+	assert.Nil(t, json.Unmarshal([]byte(data), &values))
+	assert.Equal(t, 7, values[0].Severity)
+	assert.Equal(t, 4625, values[0].LogEventID)
+	assert.Equal(t, "Security", values[0].Source)
+	assert.Equal(t, 0, values[1].Severity)
+}
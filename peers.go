@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"time"
+)
+
+// scales the median absolute deviation to the standard deviation of a normal distribution
+const madScale = 1.4826
+
+/**
+ * Robust z-score of every value against the median and the median absolute deviation of all values
+ */
+func robustScores(values []float64) (float64, []float64) {
+	center := median(values)
+	absolute := make([]float64, len(values))
+	for i, value := range values {
+		absolute[i] = math.Abs(value - center)
+	}
+	spread := madScale * median(absolute)
+
+	scores := make([]float64, len(values))
+	for i, value := range values {
+		scores[i] = math.NaN()
+		if spread > 0 {
+			scores[i] = (value - center) / spread
+		}
+	}
+	return center, scores
+}
+
+/**
+ * Compare the latest value of every item with the items of the same key on the other hosts
+ */
+func processPeers(session zabbix.Session, items []zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.Peers
+	minimum := configuration.Minimum
+	if minimum == 0 {
+		minimum = 3
+	}
+	window := time.Duration(configuration.Window) * time.Second
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, "score")
+
+	groups := make(map[string][]zabbix.ItemResponseElement)
+	for _, item := range items {
+		groups[item.Key] = append(groups[item.Key], item)
+	}
+
+	now := time.Now()
+	for key, group := range groups {
+		members := make([]zabbix.ItemResponseElement, 0, len(group))
+		latest := make([]sample, 0, len(group))
+		for _, item := range group {
+			series := loadSeries(session, item, now.Add(-window), now, "history")
+			if len(series) > 0 {
+				members = append(members, item)
+				latest = append(latest, series[len(series)-1])
+			}
+		}
+		if len(members) < minimum {
+			Log.Warn("not enough peers", "key", key, "hosts", len(members), "minimum", minimum)
+			continue
+		}
+
+		values := make([]float64, len(latest))
+		for i, s := range latest {
+			values[i] = s.value
+		}
+		center, scores := robustScores(values)
+		Log.Info("peer group", "key", key, "hosts", len(members), "median", center)
+		for i, item := range members {
+			result := map[string]float64{"score": scores[i], "median": center, "difference": values[i] - center}
+			emitOutputs(item, outputs, latest[i].time, result)
+		}
+	}
+}
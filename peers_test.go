@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestRobustScores(t *testing.T) {
+	center, scores := robustScores([]float64{10, 11, 9, 10, 40})
+	assert.Equal(t, 10.0, center)
+	assert.InDelta(t, 0, scores[0], 1e-9)
+	assert.InDelta(t, 1/madScale, scores[1], 1e-9)
+	assert.InDelta(t, 30/madScale, scores[4], 1e-9)
+}
+
+func TestRobustScoresWithoutSpread(t *testing.T) {
+	center, scores := robustScores([]float64{5, 5, 5, 7})
+	assert.Equal(t, 5.0, center)
+	assert.True(t, math.IsNaN(scores[3]))
+}
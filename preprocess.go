@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Counter preprocessing by item ID, applied whenever values of the item are loaded while its configuration is processed
+var itemPreprocessing = make(map[string]zabbix.PreprocessingConfiguration)
+
+/**
+ * Use the preprocessing for the history of the items until the returned function is called.
+ * Without preprocessing mode the items are loaded unchanged
+ */
+func applyPreprocessing(items []zabbix.ItemResponseElement, configuration zabbix.PreprocessingConfiguration) func() {
+	for _, item := range items {
+		if configuration.Mode != "" {
+			itemPreprocessing[item.ItemID] = configuration
+		} else {
+			delete(itemPreprocessing, item.ItemID)
+		}
+	}
+	return func() {
+		for _, item := range items {
+			delete(itemPreprocessing, item.ItemID)
+		}
+	}
+}
+
+/**
+ * Convert a counter series (oldest first) to the change per sample or per second.
+ * The result has one value less than the series.
+ */
+func preprocess(series []sample, configuration zabbix.PreprocessingConfiguration) []sample {
+	if len(series) < 2 {
+		return []sample{}
+	}
+	result := make([]sample, 0, len(series)-1)
+	for i := 1; i < len(series); i++ {
+		previous := series[i-1]
+		current := series[i]
+		delta := counterDelta(previous.value, current.value, configuration.Counter)
+		if configuration.Mode == "rate" {
+			seconds := current.time.Sub(previous.time).Seconds()
+			if seconds <= 0 {
+				continue
+			}
+			delta = delta / seconds
+		}
+		result = append(result, sample{time: current.time, value: delta})
+	}
+	return result
+}
+
+/**
+ * Increase of a counter. A decrease is a wrap if the counter size is known and the wrapped
+ * increase is smaller than half of the counter range, otherwise a reset to zero.
+ */
+func counterDelta(previous float64, current float64, bits int) float64 {
+	if current >= previous {
+		return current - previous
+	}
+	if bits > 0 {
+		size := math.Pow(2, float64(bits))
+		if wrapped := size - previous + current; wrapped < size/2 {
+			return wrapped
+		}
+	}
+	return current
+}
+
+/**
+ * Apply the preprocessing of the item to history values as returned by the API (newest first)
+ */
+func preprocessHistory(itemID string, values []zabbix.HistoryValue) []zabbix.HistoryValue {
+	configuration, found := itemPreprocessing[itemID]
+	if !found {
+		return values
+	}
+
+	series := historySamples(values)
+	sort.Slice(series, func(i, j int) bool { return series[i].time.Before(series[j].time) })
+
+	converted := preprocess(series, configuration)
+	result := make([]zabbix.HistoryValue, len(converted))
+	for i, s := range converted {
+		// keep the newest first order of the API
+		result[len(converted)-1-i] = zabbix.HistoryValue{
+			Value: strconv.FormatFloat(s.value, 'f', -1, 64),
+			Item:  itemID,
+			Clock: s.time.Unix(),
+			Nano:  int64(s.time.Nanosecond()),
+		}
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCounterDelta(t *testing.T) {
+	assert.Equal(t, 5.0, counterDelta(10, 15, 0))
+	// reset without known counter size
+	assert.Equal(t, 3.0, counterDelta(10, 3, 0))
+	// 32 bit wrap
+	assert.Equal(t, 15.0, counterDelta(math.Pow(2, 32)-10, 5, 32))
+	// reset of a 32 bit counter far from the end of the range
+	assert.Equal(t, 5.0, counterDelta(1000, 5, 32))
+	// 64 bit wrap
+	assert.Equal(t, 4096.0, counterDelta(math.Pow(2, 64)-2048, 2048, 64))
+}
+
+func TestPreprocessRate(t *testing.T) {
+	series := []sample{
+		{time.Unix(0, 0), 100},
+		{time.Unix(10, 0), 200},
+		{time.Unix(20, 0), 250},
+	}
+	rates := preprocess(series, zabbix.PreprocessingConfiguration{Mode: "rate"})
+	assert.Equal(t, []sample{{time.Unix(10, 0), 10}, {time.Unix(20, 0), 5}}, rates)
+
+	deltas := preprocess(series, zabbix.PreprocessingConfiguration{Mode: "delta"})
+	assert.Equal(t, []sample{{time.Unix(10, 0), 100}, {time.Unix(20, 0), 50}}, deltas)
+	assert.Empty(t, preprocess(series[:1], zabbix.PreprocessingConfiguration{Mode: "delta"}))
+}
+
+func TestPreprocessHistory(t *testing.T) {
+	values := []zabbix.HistoryValue{{Value: "300", Clock: 20}, {Value: "100", Clock: 10}, {Value: "0", Clock: 0}}
+	assert.Equal(t, values, preprocessHistory("1", values))
+
+	itemPreprocessing["1"] = zabbix.PreprocessingConfiguration{Mode: "rate"}
+	defer delete(itemPreprocessing, "1")
+	converted := preprocessHistory("1", values)
+	assert.Equal(t, 2, len(converted))
+	assert.Equal(t, "20", converted[0].Value)
+	assert.Equal(t, int64(20), converted[0].Clock)
+	assert.Equal(t, "10", converted[1].Value)
+}
+
+func TestApplyPreprocessing(t *testing.T) {
+	items := []zabbix.ItemResponseElement{{ItemID: "1"}, {ItemID: "2"}}
+	itemPreprocessing["2"] = zabbix.PreprocessingConfiguration{Mode: "delta"}
+
+	reset := applyPreprocessing(items, zabbix.PreprocessingConfiguration{Mode: "rate"})
+	assert.Equal(t, "rate", itemPreprocessing["1"].Mode)
+	assert.Equal(t, "rate", itemPreprocessing["2"].Mode)
+	reset()
+	assert.Empty(t, itemPreprocessing)
+
+	itemPreprocessing["2"] = zabbix.PreprocessingConfiguration{Mode: "delta"}
+	reset = applyPreprocessing(items, zabbix.PreprocessingConfiguration{})
+	assert.Empty(t, itemPreprocessing)
+	reset()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"time"
+)
+
+/**
+ * Quality figures of the values (newest first) found in the window before now, keyed by output type
+ */
+func qualityValues(values []zabbix.HistoryValue, last time.Time, now time.Time, window time.Duration, delay zabbix.Delay, flatline int) map[string]float64 {
+	age := now.Sub(last).Seconds()
+	result := map[string]float64{
+		"age":          age,
+		"agefactor":    math.NaN(),
+		"missing":      math.NaN(),
+		"missingratio": math.NaN(),
+		"unchanged":    0,
+		"flatline":     0,
+	}
+	if interval := delay.IntervalAt(last); interval > 0 {
+		result["agefactor"] = age / interval.Seconds()
+	}
+	if delay.Interval > 0 || len(delay.Flexible) > 0 || len(delay.Scheduling) > 0 {
+		expected := float64(delay.ExpectedSamples(now.Add(-window), now))
+		missing := math.Max(0, expected-float64(len(values)))
+		result["missing"] = missing
+		if expected > 0 {
+			result["missingratio"] = missing / expected
+		}
+	}
+
+	unchanged := 0
+	for i := 1; i < len(values) && values[i].Value == values[0].Value; i++ {
+		unchanged++
+	}
+	result["unchanged"] = float64(unchanged)
+	if unchanged >= flatline {
+		result["flatline"] = 1
+	}
+	return result
+}
+
+/**
+ * Report age, missing samples and flatline of the item
+ */
+func processQuality(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.Quality
+	window := time.Duration(configuration.Window) * time.Second
+	flatline := configuration.Flatline
+	if flatline == 0 {
+		flatline = 10
+	}
+	delay, err := session.ItemDelay(item)
+	if err != nil {
+		Log.Warn("cannot interpret item delay, only reporting the age", "item", item.ItemID, "delay", item.Delay, "error", err)
+	}
+
+	now := time.Now()
+	latest := session.NewHistoryQuery()
+	latest.ValueType = item.ValueType
+	latest.Items = []string{item.ItemID}
+	latest.Limit = 1
+	last := latest.Query()
+	if len(last) == 0 {
+		Log.Warn("item has no history at all", "item", item.ItemID, "key", item.Key)
+		return
+	}
+
+	query := session.NewHistoryQuery()
+	query.ValueType = item.ValueType
+	query.Items = []string{item.ItemID}
+	query.From = now.Add(-window).Unix()
+	query.To = now.Unix()
+	values := query.Query()
+
+	result := qualityValues(values, time.Unix(last[0].Clock, last[0].Nano), now, window, delay, flatline)
+	Log.Info("data quality", "item", item.ItemID, "age", result["age"], "missing", result["missing"], "unchanged", result["unchanged"])
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, "age", "missing", "flatline")
+	emitOutputs(item, outputs, now, result)
+}
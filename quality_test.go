@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestQualityValues(t *testing.T) {
+	now := time.Unix(1000, 0)
+	values := []zabbix.HistoryValue{{Value: "1", Clock: 940}, {Value: "1", Clock: 880}, {Value: "2", Clock: 820}}
+	result := qualityValues(values, time.Unix(940, 0), now, 10*time.Minute, zabbix.Delay{Interval: time.Minute}, 2)
+	assert.Equal(t, 60.0, result["age"])
+	assert.Equal(t, 1.0, result["agefactor"])
+	assert.Equal(t, 7.0, result["missing"])
+	assert.Equal(t, 0.7, result["missingratio"])
+	assert.Equal(t, 1.0, result["unchanged"])
+	assert.Equal(t, 0.0, result["flatline"])
+
+	result = qualityValues(values[:2], time.Unix(940, 0), now, 10*time.Minute, zabbix.Delay{}, 1)
+	assert.Equal(t, 1.0, result["flatline"])
+	assert.True(t, math.IsNaN(result["missing"]))
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"html/template"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+/**
+ * Deviation of an item from its past weeks
+ */
+type deviationEntry struct {
+	hostID   string
+	Host     string
+	Name     string
+	Key      string
+	Current  float64
+	Baseline float64   // average of the samples
+	Absolute float64   // current - baseline
+	Relative float64   // percent of the baseline. NaN if the baseline is 0
+	Samples  []float64 // historic values the baseline is computed from
+}
+
+/**
+ * Ranked deviations of a host group, or of all hosts if not grouped
+ */
+type reportSection struct {
+	Group      string
+	Deviations []deviationEntry
+}
+
+// pastweeks comparisons of this run, written with -report
+var deviationEntries = make([]deviationEntry, 0)
+
+/**
+ * Remember the pastweeks comparison of the item for the report
+ */
+func recordDeviation(item zabbix.ItemResponseElement, comparison weekComparison, values map[string]float64) {
+	if len(comparison.samples) == 0 {
+		return
+	}
+	deviationEntries = append(deviationEntries, deviationEntry{
+		hostID: item.HostID, Host: hosts[item.HostID], Name: item.Name, Key: item.Key,
+		Current: comparison.current, Baseline: average(comparison.samples),
+		Absolute: values["absolute"], Relative: values["percent"], Samples: comparison.samples,
+	})
+}
+
+/**
+ * Sort by the absolute value of the absolute (default) or relative deviation, largest first. NaN sorts last
+ */
+func sortDeviations(entries []deviationEntry, by string) {
+	magnitude := func(entry deviationEntry) float64 {
+		value := entry.Absolute
+		if by == "relative" {
+			value = entry.Relative
+		}
+		if math.IsNaN(value) {
+			return -1
+		}
+		return math.Abs(value)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return magnitude(entries[i]) > magnitude(entries[j]) })
+}
+
+/**
+ * Sorted and limited sections. One section per host group if grouped, hosts without group go to "(none)"
+ */
+func reportSections(entries []deviationEntry, configuration zabbix.ReportConfiguration) []reportSection {
+	groups := map[string][]deviationEntry{"": entries}
+	if configuration.Group {
+		groups = make(map[string][]deviationEntry)
+		for _, entry := range entries {
+			names := hostGroups[entry.hostID]
+			if len(names) == 0 {
+				names = []string{"(none)"}
+			}
+			for _, name := range names {
+				groups[name] = append(groups[name], entry)
+			}
+		}
+	}
+	sections := make([]reportSection, 0, len(groups))
+	for name := range groups {
+		deviations := append([]deviationEntry(nil), groups[name]...)
+		sortDeviations(deviations, configuration.Sort)
+		if configuration.Limit > 0 && len(deviations) > configuration.Limit {
+			deviations = deviations[:configuration.Limit]
+		}
+		sections = append(sections, reportSection{Group: name, Deviations: deviations})
+	}
+	sort.Slice(sections, func(i, j int) bool { return sections[i].Group < sections[j].Group })
+	return sections
+}
+
+/**
+ * Number with up to 6 significant digits, - if not a number
+ */
+func formatValue(value float64) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return "-"
+	}
+	return strconv.FormatFloat(value, 'g', 6, 64)
+}
+
+/**
+ * Table cells of an entry in the column order of reportColumns
+ */
+func (entry deviationEntry) cells() []string {
+	return []string{entry.Host, entry.Name, entry.Key, formatValue(entry.Current), formatValue(entry.Baseline),
+		formatValue(entry.Absolute), formatValue(entry.Relative), strconv.Itoa(len(entry.Samples))}
+}
+
+var reportColumns = []string{"HOST", "NAME", "KEY", "CURRENT", "BASELINE", "DEVIATION", "PERCENT", "SAMPLES"}
+
+func textReport(sections []reportSection) []byte {
+	var b bytes.Buffer
+	for i, section := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if section.Group != "" {
+			fmt.Fprintf(&b, "%s\n", section.Group)
+		}
+		writer := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(reportColumns, "\t"))
+		for _, entry := range section.Deviations {
+			fmt.Fprintln(writer, strings.Join(entry.cells(), "\t"))
+		}
+		writer.Flush()
+	}
+	return b.Bytes()
+}
+
+func markdownReport(sections []reportSection) []byte {
+	var b bytes.Buffer
+	escape := strings.NewReplacer("|", `\|`)
+	for i, section := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if section.Group != "" {
+			fmt.Fprintf(&b, "## %s\n\n", section.Group)
+		}
+		fmt.Fprintf(&b, "| %s |\n", strings.Join(reportColumns, " | "))
+		fmt.Fprintf(&b, "|%s\n", strings.Repeat("---|", len(reportColumns)))
+		for _, entry := range section.Deviations {
+			cells := entry.cells()
+			for c := range cells {
+				cells[c] = escape.Replace(cells[c])
+			}
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(cells, " | "))
+		}
+	}
+	return b.Bytes()
+}
+
+// json has no NaN, such values are null
+func jsonValue(value float64) *float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil
+	}
+	return &value
+}
+
+/**
+ * Entries as list, or list of groups with their entries if grouped
+ */
+func jsonReport(sections []reportSection, grouped bool) ([]byte, error) {
+	type jsonEntry struct {
+		Host      string    `json:"host"`
+		Name      string    `json:"name"`
+		Key       string    `json:"key"`
+		Current   *float64  `json:"current"`
+		Baseline  *float64  `json:"baseline"`
+		Deviation *float64  `json:"deviation"`
+		Percent   *float64  `json:"percent"`
+		Samples   []float64 `json:"samples"`
+	}
+	type jsonSection struct {
+		Group      string      `json:"group"`
+		Deviations []jsonEntry `json:"deviations"`
+	}
+	result := make([]jsonSection, 0, len(sections))
+	for _, section := range sections {
+		entries := make([]jsonEntry, 0, len(section.Deviations))
+		for _, entry := range section.Deviations {
+			entries = append(entries, jsonEntry{
+				Host: entry.Host, Name: entry.Name, Key: entry.Key,
+				Current: jsonValue(entry.Current), Baseline: jsonValue(entry.Baseline),
+				Deviation: jsonValue(entry.Absolute), Percent: jsonValue(entry.Relative), Samples: entry.Samples,
+			})
+		}
+		result = append(result, jsonSection{Group: section.Group, Deviations: entries})
+	}
+	if !grouped {
+		if len(result) == 0 {
+			return json.MarshalIndent(make([]jsonEntry, 0), "", "  ")
+		}
+		return json.MarshalIndent(result[0].Deviations, "", "  ")
+	}
+	return json.MarshalIndent(result, "", "  ")
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Deviations {{.Generated}}</title></head>
+<body>
+<h1>Deviations {{.Generated}}</h1>
+{{range .Sections}}{{if .Group}}<h2>{{.Group}}</h2>
+{{end}}<table>
+<tr>{{range $.Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Deviations}}<tr>{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+func htmlReport(sections []reportSection, generated time.Time) ([]byte, error) {
+	type htmlSection struct {
+		Group      string
+		Deviations []struct{ Cells []string }
+	}
+	data := struct {
+		Generated string
+		Columns   []string
+		Sections  []htmlSection
+	}{Generated: generated.Format("2006-01-02 15:04"), Columns: reportColumns}
+	for _, section := range sections {
+		rows := htmlSection{Group: section.Group}
+		for _, entry := range section.Deviations {
+			rows.Deviations = append(rows.Deviations, struct{ Cells []string }{entry.cells()})
+		}
+		data.Sections = append(data.Sections, rows)
+	}
+	var b bytes.Buffer
+	err := htmlReportTemplate.Execute(&b, data)
+	return b.Bytes(), err
+}
+
+/**
+ * Ranked deviations in the configured format: text (default) | markdown | json | html
+ */
+func formatReport(entries []deviationEntry, configuration zabbix.ReportConfiguration) ([]byte, error) {
+	sections := reportSections(entries, configuration)
+	switch configuration.Format {
+	case "", "text":
+		return textReport(sections), nil
+	case "markdown":
+		return markdownReport(sections), nil
+	case "json":
+		return jsonReport(sections, configuration.Group)
+	case "html":
+		return htmlReport(sections, time.Now())
+	}
+	return nil, fmt.Errorf("unknown report format %s", configuration.Format)
+}
+
+func writeReport(filename string, configuration zabbix.ReportConfiguration) error {
+	Log.Info("writing deviation report", "file", filename, "items", len(deviationEntries))
+	data, err := formatReport(deviationEntries, configuration)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
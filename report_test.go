@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"strings"
+	"testing"
+)
+
+func reportEntries() []deviationEntry {
+	return []deviationEntry{
+		{hostID: "1", Host: "web01", Name: "CPU load", Key: "system.cpu.load", Current: 4, Baseline: 1, Absolute: 3, Relative: 300, Samples: []float64{1, 1}},
+		{hostID: "2", Host: "db01", Name: "Free space", Key: "vfs.fs.size[/,free]", Current: 500, Baseline: 600, Absolute: -100, Relative: -16.6667, Samples: []float64{600}},
+		{hostID: "2", Host: "db01", Name: "Errors | total", Key: "errors", Current: 2, Baseline: 0, Absolute: 2, Relative: math.NaN(), Samples: []float64{0, 0, 0}},
+	}
+}
+
+func TestRecordDeviation(t *testing.T) {
+	defer func() { deviationEntries = make([]deviationEntry, 0) }()
+	item := zabbix.ItemResponseElement{HostID: "1", Key: "system.cpu.load", Name: "CPU load"}
+	comparison := weekComparison{current: 4, samples: []float64{1, 3}}
+	recordDeviation(item, comparison, deviations(comparison.current, comparison.samples))
+	recordDeviation(item, weekComparison{current: 4}, map[string]float64{})
+	assert.Len(t, deviationEntries, 1)
+	assert.Equal(t, 2.0, deviationEntries[0].Baseline)
+	assert.Equal(t, 2.0, deviationEntries[0].Absolute)
+	assert.Equal(t, 100.0, deviationEntries[0].Relative)
+}
+
+func TestReportSections(t *testing.T) {
+	sections := reportSections(reportEntries(), zabbix.ReportConfiguration{})
+	assert.Len(t, sections, 1)
+	assert.Equal(t, []string{"vfs.fs.size[/,free]", "system.cpu.load", "errors"},
+		[]string{sections[0].Deviations[0].Key, sections[0].Deviations[1].Key, sections[0].Deviations[2].Key})
+
+	sections = reportSections(reportEntries(), zabbix.ReportConfiguration{Sort: "relative", Limit: 2})
+	assert.Len(t, sections[0].Deviations, 2)
+	assert.Equal(t, "system.cpu.load", sections[0].Deviations[0].Key)
+	assert.Equal(t, "vfs.fs.size[/,free]", sections[0].Deviations[1].Key)
+
+	defer func(groups map[string][]string) { hostGroups = groups }(hostGroups)
+	hostGroups = map[string][]string{"1": {"Web", "Linux"}}
+	sections = reportSections(reportEntries(), zabbix.ReportConfiguration{Group: true, Limit: 1})
+	assert.Equal(t, []string{"(none)", "Linux", "Web"}, []string{sections[0].Group, sections[1].Group, sections[2].Group})
+	assert.Equal(t, "vfs.fs.size[/,free]", sections[0].Deviations[0].Key)
+	assert.Equal(t, "system.cpu.load", sections[2].Deviations[0].Key)
+}
+
+func TestFormatReport(t *testing.T) {
+	text, err := formatReport(reportEntries(), zabbix.ReportConfiguration{})
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(text)), "\n")
+	assert.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[0], "HOST"))
+	assert.Contains(t, lines[1], "-16.6667")
+	assert.Contains(t, lines[3], " - ")
+
+	markdown, err := formatReport(reportEntries(), zabbix.ReportConfiguration{Format: "markdown"})
+	assert.Nil(t, err)
+	assert.Contains(t, string(markdown), "| HOST | NAME |")
+	assert.Contains(t, string(markdown), `Errors \| total`)
+
+	data, err := formatReport(reportEntries(), zabbix.ReportConfiguration{Format: "json", Limit: 1})
+	assert.Nil(t, err)
+	var decoded []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Len(t, decoded, 1)
+	assert.Equal(t, "db01", decoded[0]["host"])
+	assert.Equal(t, []interface{}{600.0}, decoded[0]["samples"])
+
+	data, err = formatReport(reportEntries(), zabbix.ReportConfiguration{Format: "json", Sort: "relative"})
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Nil(t, decoded[2]["percent"])
+
+	html, err := formatReport(reportEntries(), zabbix.ReportConfiguration{Format: "html"})
+	assert.Nil(t, err)
+	assert.Contains(t, string(html), "<td>vfs.fs.size[/,free]</td>")
+
+	_, err = formatReport(reportEntries(), zabbix.ReportConfiguration{Format: "pdf"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"strconv"
+	"time"
+)
+
+/**
+ * Conversion of a series into fixed interval buckets
+ */
+type resampler struct {
+	interval    time.Duration
+	aggregation string         // avg | min | max | last | count | sum
+	fill        string         // none | previous | linear
+	location    *time.Location // bucket boundaries aligned to this timezone. not aligned if nil
+}
+
+/**
+ * Resampler of the configuration. Defaults to averages with the previous value filling gaps
+ */
+func newResampler(configuration zabbix.ResampleConfiguration, interval time.Duration) resampler {
+	r := resampler{interval: interval, aggregation: configuration.Aggregation, fill: configuration.Fill}
+	switch r.aggregation {
+	case "avg", "min", "max", "last", "count", "sum":
+	default:
+		if r.aggregation != "" {
+			Log.Warn("unknown aggregation, using avg", "aggregation", r.aggregation)
+		}
+		r.aggregation = "avg"
+	}
+	switch r.fill {
+	case "none", "previous", "linear":
+	default:
+		if r.fill != "" {
+			Log.Warn("unknown gap fill, using previous", "fill", r.fill)
+		}
+		r.fill = "previous"
+	}
+	if configuration.Timezone != "" {
+		location, err := time.LoadLocation(configuration.Timezone)
+		if err != nil {
+			Log.Warn("unknown timezone, using local time", "timezone", configuration.Timezone, "error", err)
+			location = time.Local
+		}
+		r.location = location
+	}
+	return r
+}
+
+/**
+ * Last bucket boundary at or before t: a multiple of the interval since midnight, midnight for daily
+ * and monday midnight for weekly intervals in the timezone. t if the resampler is not aligned
+ */
+func (r resampler) align(t time.Time) time.Time {
+	if r.location == nil {
+		return t
+	}
+	t = t.In(r.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, r.location)
+	switch {
+	case r.interval >= time.Hour*24*7:
+		return midnight.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+	case r.interval >= time.Hour*24:
+		return midnight
+	}
+	return midnight.Add(t.Sub(midnight) / r.interval * r.interval)
+}
+
+/**
+ * End of the bucket containing t: the first boundary at or after t, so the latest sample is not dropped.
+ * t if the resampler is not aligned
+ */
+func (r resampler) ceil(t time.Time) time.Time {
+	boundary := r.align(t)
+	if !boundary.Before(t) {
+		return boundary
+	}
+	return r.step(boundary, 1)
+}
+
+/**
+ * The boundary n buckets after t, before t for negative n. Aligned daily and weekly buckets step in
+ * calendar days, so they keep their boundaries across daylight saving changes
+ */
+func (r resampler) step(t time.Time, n int) time.Time {
+	if r.location != nil && r.interval >= time.Hour*24 {
+		return t.In(r.location).AddDate(0, 0, n*int(r.interval/(time.Hour*24)))
+	}
+	return t.Add(r.interval * time.Duration(n))
+}
+
+/**
+ * Aggregate the samples into count buckets ending at end. A bucket contains the samples in (start, start + interval].
+ * Empty buckets are 0 for count and sum, otherwise NaN until filled. Leading and, for linear, trailing gaps stay NaN.
+ */
+func (r resampler) resample(series []sample, end time.Time, count int) []float64 {
+	buckets := make([]float64, count)
+	counts := make([]int, count)
+	for i := range buckets {
+		buckets[i] = math.NaN()
+	}
+	start := r.step(end, -count)
+	for _, s := range series {
+		if !s.time.After(start) || s.time.After(end) {
+			continue
+		}
+		index := int((s.time.Sub(start) - 1) / r.interval)
+		// calendar day buckets are an hour shorter or longer across daylight saving changes
+		for index > 0 && !s.time.After(r.step(start, index)) {
+			index--
+		}
+		for index < count-1 && s.time.After(r.step(start, index+1)) {
+			index++
+		}
+		if counts[index] == 0 {
+			buckets[index] = s.value
+		} else {
+			switch r.aggregation {
+			case "min":
+				buckets[index] = math.Min(buckets[index], s.value)
+			case "max":
+				buckets[index] = math.Max(buckets[index], s.value)
+			case "last":
+				buckets[index] = s.value
+			default:
+				buckets[index] += s.value
+			}
+		}
+		counts[index]++
+	}
+
+	for i := range buckets {
+		switch {
+		case r.aggregation == "count":
+			buckets[i] = float64(counts[i])
+		case r.aggregation == "sum" && counts[i] == 0:
+			buckets[i] = 0
+		case r.aggregation == "avg" && counts[i] > 0:
+			buckets[i] /= float64(counts[i])
+		}
+	}
+	return fillGaps(buckets, r.fill)
+}
+
+/**
+ * Replace NaN buckets with the previous value or the linear interpolation of the neighbours
+ */
+func fillGaps(buckets []float64, fill string) []float64 {
+	previous := -1
+	for i := range buckets {
+		if math.IsNaN(buckets[i]) {
+			continue
+		}
+		if previous >= 0 && i-previous > 1 {
+			for j := previous + 1; j < i; j++ {
+				switch fill {
+				case "previous":
+					buckets[j] = buckets[previous]
+				case "linear":
+					buckets[j] = buckets[previous] + (buckets[i]-buckets[previous])*float64(j-previous)/float64(i-previous)
+				}
+			}
+		}
+		previous = i
+	}
+	if fill == "previous" && previous >= 0 {
+		for j := previous + 1; j < len(buckets); j++ {
+			buckets[j] = buckets[previous]
+		}
+	}
+	return buckets
+}
+
+/**
+ * Numeric history values as series in the order of the values
+ */
+func historySamples(values []zabbix.HistoryValue) []sample {
+	series := make([]sample, 0, len(values))
+	for _, history := range values {
+		value, err := strconv.ParseFloat(history.Value, 64)
+		if err == nil {
+			series = append(series, sample{time: time.Unix(history.Clock, history.Nano), value: value})
+		}
+	}
+	return series
+}
+
+/**
+ * Hourly trend values as series. field selects avg (default), min or max
+ */
+func trendSamples(values []zabbix.TrendValue, field string) []sample {
+	series := make([]sample, 0, len(values))
+	for _, trend := range values {
+		text := trend.AvgValue
+		switch field {
+		case "min":
+			text = trend.MinValue
+		case "max":
+			text = trend.MaxValue
+		}
+		value, err := strconv.ParseFloat(text, 64)
+		if err == nil {
+			series = append(series, sample{time: time.Unix(trend.Clock, 0), value: value})
+		}
+	}
+	return series
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestResampleAggregations(t *testing.T) {
+	series := []sample{
+		{time.Unix(10, 0), 4}, {time.Unix(50, 0), 2}, {time.Unix(100, 0), 6},
+		{time.Unix(250, 0), 1},
+	}
+	end := time.Unix(300, 0)
+	expected := map[string][]float64{
+		"avg":   {4, math.NaN(), 1},
+		"min":   {2, math.NaN(), 1},
+		"max":   {6, math.NaN(), 1},
+		"last":  {6, math.NaN(), 1},
+		"count": {3, 0, 1},
+		"sum":   {12, 0, 1},
+	}
+	for aggregation, buckets := range expected {
+		r := newResampler(zabbix.ResampleConfiguration{Aggregation: aggregation, Fill: "none"}, 100*time.Second)
+		result := r.resample(series, end, 3)
+		for i := range buckets {
+			if math.IsNaN(buckets[i]) {
+				assert.True(t, math.IsNaN(result[i]), aggregation)
+			} else {
+				assert.Equal(t, buckets[i], result[i], aggregation)
+			}
+		}
+	}
+}
+
+func TestResampleFill(t *testing.T) {
+	series := []sample{{time.Unix(150, 0), 2}, {time.Unix(450, 0), 8}}
+	end := time.Unix(600, 0)
+
+	linear := newResampler(zabbix.ResampleConfiguration{Fill: "linear"}, 100*time.Second).resample(series, end, 6)
+	assert.True(t, math.IsNaN(linear[0]))
+	assert.Equal(t, []float64{2, 4, 6, 8}, linear[1:5])
+	assert.True(t, math.IsNaN(linear[5]))
+
+	previous := newResampler(zabbix.ResampleConfiguration{}, 100*time.Second).resample(series, end, 6)
+	assert.Equal(t, []float64{2, 2, 2, 8, 8}, previous[1:])
+
+	none := newResampler(zabbix.ResampleConfiguration{Fill: "none"}, 100*time.Second).resample(series, end, 6)
+	assert.True(t, math.IsNaN(none[2]))
+}
+
+func TestResampleDefaults(t *testing.T) {
+	r := newResampler(zabbix.ResampleConfiguration{Aggregation: "median", Fill: "spline", Timezone: "Nowhere/Atlantis"}, time.Hour)
+	assert.Equal(t, "avg", r.aggregation)
+	assert.Equal(t, "previous", r.fill)
+	assert.Equal(t, time.Local, r.location)
+	assert.Nil(t, newResampler(zabbix.ResampleConfiguration{}, time.Hour).location)
+}
+
+func TestResampleAlign(t *testing.T) {
+	at := time.Date(2019, 3, 13, 15, 47, 12, 0, time.UTC)
+	assert.Equal(t, at, newResampler(zabbix.ResampleConfiguration{}, time.Hour).align(at))
+
+	utc := zabbix.ResampleConfiguration{Timezone: "UTC"}
+	assert.Equal(t, time.Date(2019, 3, 13, 15, 45, 0, 0, time.UTC), newResampler(utc, 15*time.Minute).align(at).UTC())
+	assert.Equal(t, time.Date(2019, 3, 13, 12, 0, 0, 0, time.UTC), newResampler(utc, 6*time.Hour).align(at).UTC())
+	assert.Equal(t, time.Date(2019, 3, 13, 0, 0, 0, 0, time.UTC), newResampler(utc, 24*time.Hour).align(at).UTC())
+	assert.Equal(t, time.Date(2019, 3, 11, 0, 0, 0, 0, time.UTC), newResampler(utc, 7*24*time.Hour).align(at).UTC())
+
+	if location, err := time.LoadLocation("Asia/Kolkata"); err == nil {
+		// UTC+5:30: hourly boundaries are at half past in UTC
+		aligned := newResampler(zabbix.ResampleConfiguration{Timezone: "Asia/Kolkata"}, time.Hour).align(at)
+		assert.Equal(t, time.Date(2019, 3, 13, 21, 0, 0, 0, location), aligned)
+		assert.Equal(t, 30, aligned.UTC().Minute())
+	}
+}
+
+func TestResampleCeil(t *testing.T) {
+	at := time.Date(2019, 3, 13, 15, 47, 12, 0, time.UTC)
+	assert.Equal(t, at, newResampler(zabbix.ResampleConfiguration{}, time.Hour).ceil(at))
+
+	utc := zabbix.ResampleConfiguration{Timezone: "UTC"}
+	sampler := newResampler(utc, 15*time.Minute)
+	assert.Equal(t, time.Date(2019, 3, 13, 16, 0, 0, 0, time.UTC), sampler.ceil(at).UTC())
+	boundary := time.Date(2019, 3, 13, 15, 45, 0, 0, time.UTC)
+	assert.Equal(t, boundary, sampler.ceil(boundary).UTC())
+	assert.Equal(t, time.Date(2019, 3, 14, 0, 0, 0, 0, time.UTC), newResampler(utc, 24*time.Hour).ceil(at).UTC())
+	assert.Equal(t, time.Date(2019, 3, 18, 0, 0, 0, 0, time.UTC), newResampler(utc, 7*24*time.Hour).ceil(at).UTC())
+
+	// the latest sample is in the last bucket
+	series := []sample{{time: boundary.Add(-time.Minute), value: 1}, {time: at, value: 5}}
+	assert.Equal(t, []float64{1, 5}, sampler.resample(series, sampler.ceil(at), 2))
+}
+
+func TestResampleDaylightSaving(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Skip("no timezone database")
+	}
+	// summer time starts on 2019-03-31, the day has 23 hours
+	sampler := newResampler(zabbix.ResampleConfiguration{Timezone: "Europe/Zurich"}, 24*time.Hour)
+	end := time.Date(2019, 4, 2, 0, 0, 0, 0, zurich)
+	assert.Equal(t, time.Date(2019, 3, 30, 0, 0, 0, 0, zurich), sampler.step(end, -3))
+	series := []sample{
+		{time: time.Date(2019, 3, 30, 23, 30, 0, 0, zurich), value: 1},
+		{time: time.Date(2019, 3, 31, 23, 30, 0, 0, zurich), value: 2},
+		{time: time.Date(2019, 4, 1, 0, 30, 0, 0, zurich), value: 3},
+	}
+	assert.Equal(t, []float64{1, 2, 3}, sampler.resample(series, end, 3))
+
+	weekly := newResampler(zabbix.ResampleConfiguration{Timezone: "Europe/Zurich"}, 7*24*time.Hour)
+	assert.Equal(t, time.Date(2019, 4, 1, 0, 0, 0, 0, zurich), weekly.ceil(time.Date(2019, 3, 26, 12, 0, 0, 0, zurich)))
+}
+
+func TestSamples(t *testing.T) {
+	history := historySamples([]zabbix.HistoryValue{{Value: "1.5", Clock: 10, Nano: 5}, {Value: "text", Clock: 20}})
+	assert.Equal(t, []sample{{time.Unix(10, 5), 1.5}}, history)
+
+	trends := []zabbix.TrendValue{{Clock: 3600, MinValue: "1", AvgValue: "2", MaxValue: "3"}}
+	assert.Equal(t, 2.0, trendSamples(trends, "avg")[0].value)
+	assert.Equal(t, 1.0, trendSamples(trends, "min")[0].value)
+	assert.Equal(t, 3.0, trendSamples(trends, "max")[0].value)
+}
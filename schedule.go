@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"strings"
+	"time"
+)
+
+// Item ID to the schedule restricting the samples of the baseline
+var baselineSchedules = make(map[string]zabbix.Schedule)
+
+// Item ID to the value emitted instead of the deviations and scores outside the schedule
+var neutralValues = make(map[string]float64)
+
+// Output types replaced by the neutral value. Levels like bands, forecasts or availability are emitted as computed
+var neutralTypes = map[string]bool{
+	"absolute": true, "percent": true, "ratio": true, "zscore": true, // pastweeks
+	"score": true, "outofcontrol": true, "difference": true, // ewma, peers
+	"residual": true, "remainder": true, // holtwinters, stl
+	"ks": true, "p50shift": true, "p95shift": true, "p99shift": true, // distribution
+	"magnitude": true, "shifted": true, // changepoint
+}
+
+/**
+ * Prepare the schedule of the item at now. Returns false if the item is not processed
+ */
+func scheduleItem(item zabbix.ItemResponseElement, configuration zabbix.ScheduleConfiguration, schedule zabbix.Schedule, now time.Time) bool {
+	delete(baselineSchedules, item.ItemID)
+	delete(neutralValues, item.ItemID)
+	if schedule.Contains(now) {
+		if configuration.Baseline {
+			baselineSchedules[item.ItemID] = schedule
+		}
+		return true
+	}
+	if configuration.Neutral == nil {
+		Log.Info("skipping item outside its schedule", "item", item.ItemID, "windows", configuration.Windows)
+		return false
+	}
+	// all samples are used, only the results are replaced
+	neutralValues[item.ItemID] = *configuration.Neutral
+	return true
+}
+
+/**
+ * Neutral value replacing the output of the item, if it is outside its schedule and the output a deviation or score.
+ * Log pattern changes (<pattern>.change) are deviations as well
+ */
+func neutralValue(itemID string, outputType string) (float64, bool) {
+	neutral, found := neutralValues[itemID]
+	if !found || !(neutralTypes[outputType] || strings.HasSuffix(outputType, ".change")) {
+		return 0, false
+	}
+	return neutral, true
+}
+
+/**
+ * True if the sample at t may enter the baseline of the item
+ */
+func inBaseline(itemID string, t time.Time) bool {
+	schedule, found := baselineSchedules[itemID]
+	return !found || schedule.Contains(t)
+}
+
+/**
+ * Samples of the series allowed in the baseline. Only for historic samples, the current ones are always used
+ */
+func restrictSeries(itemID string, series []sample) []sample {
+	if _, found := baselineSchedules[itemID]; !found {
+		return series
+	}
+	restricted := make([]sample, 0, len(series))
+	for _, s := range series {
+		if inBaseline(itemID, s.time) {
+			restricted = append(restricted, s)
+		}
+	}
+	return restricted
+}
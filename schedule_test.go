@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestScheduleItem(t *testing.T) {
+	item := zabbix.ItemResponseElement{ItemID: "1"}
+	schedule, err := zabbix.ParseSchedule([]string{"mon-fri 08:00-18:00"}, "UTC")
+	assert.Nil(t, err)
+	monday := time.Date(2019, 1, 7, 12, 0, 0, 0, time.UTC)
+	sunday := time.Date(2019, 1, 6, 12, 0, 0, 0, time.UTC)
+
+	configuration := zabbix.ScheduleConfiguration{Windows: []string{"mon-fri 08:00-18:00"}, Baseline: true}
+	assert.True(t, scheduleItem(item, configuration, schedule, monday))
+	assert.True(t, inBaseline("1", monday.Add(-time.Hour*24*7)))
+	assert.False(t, inBaseline("1", sunday))
+	series := []sample{{time: sunday, value: 1}, {time: monday, value: 2}}
+	assert.Equal(t, []sample{{time: monday, value: 2}}, restrictSeries("1", series))
+	assert.Equal(t, series, restrictSeries("2", series))
+
+	assert.False(t, scheduleItem(item, configuration, schedule, sunday))
+
+	neutral := 0.0
+	configuration.Neutral = &neutral
+	assert.True(t, scheduleItem(item, configuration, schedule, sunday))
+	assert.Equal(t, 0.0, neutralValues["1"])
+	value, found := neutralValue("1", "zscore")
+	assert.True(t, found)
+	assert.Equal(t, 0.0, value)
+	_, found = neutralValue("1", "errors.change")
+	assert.True(t, found)
+	_, found = neutralValue("1", "upper")
+	assert.False(t, found)
+	_, found = neutralValue("2", "zscore")
+	assert.False(t, found)
+	assert.True(t, inBaseline("1", sunday))
+
+	assert.True(t, scheduleItem(item, configuration, schedule, monday))
+	_, found = neutralValues["1"]
+	assert.False(t, found)
+	delete(baselineSchedules, "1")
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"math"
+	"time"
+)
+
+/**
+ * Dominant period of an item and the algorithm configuration suited for it
+ */
+type seasonalityRecommendation struct {
+	itemID   string
+	host     string
+	key      string
+	period   string  // daily | weekly | none
+	strength float64 // autocorrelation at the period
+}
+
+// recommendations of this run, written with -recommend
+var recommendations = make([]seasonalityRecommendation, 0)
+
+/**
+ * Sample autocorrelation at lag. Normalized by the full length, so longer lags are damped
+ */
+func autocorrelation(values []float64, lag int) float64 {
+	n := len(values)
+	if lag <= 0 || lag >= n {
+		return math.NaN()
+	}
+	average := mean(values)
+	var numerator, denominator float64
+	for i, value := range values {
+		denominator += (value - average) * (value - average)
+		if i >= lag {
+			numerator += (value - average) * (values[i-lag] - average)
+		}
+	}
+	if denominator == 0 {
+		return math.NaN()
+	}
+	return numerator / denominator
+}
+
+/**
+ * Daily or weekly if the autocorrelation at that lag reaches the threshold. none otherwise.
+ * A weekly pattern also correlates at one day, so weekly wins only if it correlates better.
+ */
+func dominantPeriod(buckets []float64, interval time.Duration, threshold float64) (string, float64) {
+	daily := autocorrelation(buckets, int(time.Hour*24/interval))
+	weekly := autocorrelation(buckets, int(time.Hour*24*7/interval))
+	if !math.IsNaN(weekly) && weekly >= threshold && (math.IsNaN(daily) || weekly > daily) {
+		return "weekly", weekly
+	}
+	if !math.IsNaN(daily) && daily >= threshold {
+		return "daily", daily
+	}
+	if math.IsNaN(daily) {
+		return "none", math.NaN()
+	}
+	return "none", daily
+}
+
+/**
+ * Enable the algorithm suited for the period, unless the configuration already enables it
+ */
+func applySeasonality(configuration zabbix.ItemConfiguration, period string) zabbix.ItemConfiguration {
+	switch period {
+	case "weekly":
+		if configuration.PastWeeks.Weeks == 0 {
+			configuration.PastWeeks.Weeks = 4
+			if len(configuration.PastWeeks.Outputs) == 0 {
+				configuration.PastWeeks.Outputs = []zabbix.OutputConfiguration{{Type: "absolute", Postfix: configuration.Postfix + ".4wd"}}
+			}
+		}
+	case "daily":
+		if configuration.HoltWinters.Lookback == 0 {
+			configuration.HoltWinters.Lookback = 14 * 24 * 3600
+			configuration.HoltWinters.Season = 24 * 3600
+			configuration.HoltWinters.Interval = 3600
+			configuration.HoltWinters.Source = "trends"
+		}
+	default:
+		if configuration.EWMA.Alpha == 0 {
+			configuration.EWMA.Alpha = 0.1
+		}
+	}
+	return configuration
+}
+
+/**
+ * Detect the dominant period of the item. Returns the configuration to process the item with
+ */
+func processSeasonality(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) zabbix.ItemConfiguration {
+	configuration := itemConfiguration.Seasonality
+	interval := time.Duration(configuration.Interval) * time.Second
+	if interval == 0 {
+		interval = time.Hour
+	}
+	threshold := configuration.Threshold
+	if threshold == 0 {
+		threshold = 0.3
+	}
+	source := configuration.Source
+	if source == "" {
+		source = "trends"
+	}
+
+	now := time.Now()
+	lookback := time.Duration(configuration.Lookback) * time.Second
+	series := loadSeries(session, item, now.Add(-lookback), now, source)
+	if len(series) == 0 {
+		Log.Warn("skipping seasonality detection due to missing data", "item", item.ItemID)
+		return itemConfiguration
+	}
+	sampler := newResampler(itemConfiguration.Resample, interval)
+	buckets := skipMissing(sampler.resample(series, sampler.ceil(now), int(lookback/interval)))
+	period, strength := dominantPeriod(buckets, interval, threshold)
+	Log.Info("seasonality", "item", item.ItemID, "buckets", len(buckets), "period", period, "strength", strength)
+
+	recommendations = append(recommendations, seasonalityRecommendation{
+		itemID: item.ItemID, host: hosts[item.HostID], key: item.Key, period: period, strength: strength,
+	})
+	seconds := map[string]float64{"daily": 24 * 3600, "weekly": 7 * 24 * 3600, "none": 0}
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration)
+	emitOutputs(item, outputs, now, map[string]float64{"period": seconds[period], "strength": strength})
+
+	if configuration.Apply {
+		return applySeasonality(itemConfiguration, period)
+	}
+	return itemConfiguration
+}
+
+/**
+ * Item configuration fragment with the recommended algorithm per item
+ */
+func recommendationFragment(recommendations []seasonalityRecommendation) ([]byte, error) {
+	items := make([]yaml.MapSlice, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		item := yaml.MapSlice{
+			{Key: fmt.Sprintf("%s %s", recommendation.host, recommendation.key), Value: nil},
+			{Key: "filter", Value: yaml.MapSlice{{Key: "itemid", Value: []string{recommendation.itemID}}}},
+		}
+		switch recommendation.period {
+		case "weekly":
+			item = append(item, yaml.MapSlice{{Key: "pastweeks", Value: yaml.MapSlice{{Key: "weeks", Value: 4}}}, {Key: "postfix", Value: ".4wd"}}...)
+		case "daily":
+			item = append(item, yaml.MapSlice{{Key: "holtwinters", Value: yaml.MapSlice{
+				{Key: "lookback", Value: 14 * 24 * 3600},
+				{Key: "season", Value: 24 * 3600},
+				{Key: "interval", Value: 3600},
+				{Key: "source", Value: "trends"},
+			}}}...)
+		default:
+			item = append(item, yaml.MapSlice{{Key: "ewma", Value: yaml.MapSlice{{Key: "alpha", Value: 0.1}}}}...)
+		}
+		items = append(items, item)
+	}
+	return yaml.Marshal(yaml.MapSlice{{Key: "items", Value: items}})
+}
+
+/**
+ * Write the recommendations of this run as configuration fragment
+ */
+func writeRecommendations(filename string) error {
+	data, err := recommendationFragment(recommendations)
+	if err != nil {
+		return err
+	}
+	Log.Info("writing seasonality recommendations", "file", filename, "items", len(recommendations))
+	return ioutil.WriteFile(filename, data, 0644)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	"math"
+	"testing"
+	"time"
+)
+
+// hourly buckets of three weeks with a daily cycle and optionally lower weekends
+func seasonalBuckets(weekend float64) []float64 {
+	buckets := make([]float64, 21*24)
+	for i := range buckets {
+		buckets[i] = 10 + 5*math.Sin(2*math.Pi*float64(i%24)/24)
+		if (i/24)%7 >= 5 {
+			buckets[i] -= weekend
+		}
+	}
+	return buckets
+}
+
+func TestAutocorrelation(t *testing.T) {
+	buckets := seasonalBuckets(0)
+	assert.InDelta(t, 1.0*(21-1)/21, autocorrelation(buckets, 24), 1e-9)
+	assert.Less(t, autocorrelation(buckets, 12), -0.9)
+	assert.True(t, math.IsNaN(autocorrelation(buckets, len(buckets))))
+	assert.True(t, math.IsNaN(autocorrelation([]float64{1, 1, 1}, 1)))
+}
+
+func TestDominantPeriod(t *testing.T) {
+	period, strength := dominantPeriod(seasonalBuckets(0), time.Hour, 0.3)
+	assert.Equal(t, "daily", period)
+	assert.Greater(t, strength, 0.9)
+
+	period, _ = dominantPeriod(seasonalBuckets(10), time.Hour, 0.3)
+	assert.Equal(t, "weekly", period)
+
+	noise := make([]float64, 21*24)
+	for i := range noise {
+		noise[i] = float64((i * 7919) % 13)
+	}
+	period, _ = dominantPeriod(noise, time.Hour, 0.3)
+	assert.Equal(t, "none", period)
+
+	// too short for a weekly lag
+	period, _ = dominantPeriod(seasonalBuckets(10)[:3*24], time.Hour, 0.3)
+	assert.Equal(t, "daily", period)
+}
+
+func TestApplySeasonality(t *testing.T) {
+	configuration := applySeasonality(zabbix.ItemConfiguration{Postfix: ".x"}, "weekly")
+	assert.Equal(t, 4, configuration.PastWeeks.Weeks)
+	assert.Equal(t, ".x.4wd", configuration.PastWeeks.Outputs[0].Postfix)
+
+	configured := zabbix.ItemConfiguration{}
+	configured.HoltWinters.Lookback = 100
+	assert.Equal(t, int64(100), applySeasonality(configured, "daily").HoltWinters.Lookback)
+	assert.Equal(t, int64(86400), applySeasonality(zabbix.ItemConfiguration{}, "daily").HoltWinters.Season)
+	assert.Equal(t, 0.1, applySeasonality(zabbix.ItemConfiguration{}, "none").EWMA.Alpha)
+}
+
+func TestRecommendationFragment(t *testing.T) {
+	data, err := recommendationFragment([]seasonalityRecommendation{
+		{itemID: "1", host: "web01", key: "system.cpu.load", period: "weekly", strength: 0.8},
+		{itemID: "2", host: "web01", key: "net.if.in[eth0]", period: "daily", strength: 0.6},
+	})
+	assert.Nil(t, err)
+
+	var fragment zabbix.Configuration
+	assert.Nil(t, yaml.Unmarshal(data, &fragment))
+	assert.Len(t, fragment.Items, 2)
+	assert.Equal(t, []string{"1"}, fragment.Items[0].Filter["itemid"])
+	assert.Equal(t, 4, fragment.Items[0].PastWeeks.Weeks)
+	assert.Equal(t, int64(86400), fragment.Items[1].HoltWinters.Season)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"sort"
+	"time"
+)
+
+/**
+ * Numeric value of an item at a point in time
+ */
+type sample struct {
+	time  time.Time
+	value float64
+}
+
+/**
+ * Load the numeric values of an item between from and to, oldest first.
+ * Source "trends" uses the hourly averages, anything else the raw history. Counter preprocessing applies to the history only.
+ */
+func loadSeries(session zabbix.Session, item zabbix.ItemResponseElement, from time.Time, to time.Time, source string) []sample {
+	var series []sample
+	if source == "trends" {
+		query := session.NewTrendQuery([]string{item.ItemID}, from, to)
+		series = trendSamples(query.Query(), "avg")
+	} else {
+		query := session.NewHistoryQuery()
+		query.ValueType = item.ValueType
+		query.Items = []string{item.ItemID}
+		query.From = from.Unix()
+		query.To = to.Unix()
+		series = historySamples(query.Query())
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].time.Before(series[j].time) })
+	if configuration, found := itemPreprocessing[item.ItemID]; found {
+		if source == "trends" {
+			// hourly averages are no counter readings
+			Log.Warn("counter preprocessing does not apply to trends", "item", item.ItemID, "mode", configuration.Mode)
+		} else {
+			series = preprocess(series, configuration)
+		}
+	}
+	Log.Debug("loaded series", "item", item.ItemID, "source", source, "count", len(series))
+	return series
+}
+
+/**
+ * Drop leading NaN buckets
+ */
+func skipMissing(buckets []float64) []float64 {
+	for len(buckets) > 0 && math.IsNaN(buckets[0]) {
+		buckets = buckets[1:]
+	}
+	return buckets
+}
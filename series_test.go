@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSkipMissing(t *testing.T) {
+	end := time.Unix(3600, 0)
+	series := []sample{
+		{time.Unix(1000, 0), 1},
+		{time.Unix(1900, 0), 3},
+		{time.Unix(2000, 0), 5},
+		{time.Unix(3600, 0), 7},
+	}
+	buckets := resampler{interval: time.Second * 900, aggregation: "avg", fill: "previous"}.resample(series, end, 4)
+	assert.True(t, math.IsNaN(buckets[0]))
+	assert.Equal(t, []float64{1, 4, 7}, skipMissing(buckets))
+	assert.Empty(t, skipMissing([]float64{math.NaN()}))
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"io/ioutil"
+	"strconv"
+	"time"
+)
+
+/**
+ * Availability of an item over a calendar period
+ */
+type slaResult struct {
+	host     string
+	key      string
+	from     time.Time
+	to       time.Time
+	up       time.Duration
+	down     time.Duration
+	excluded time.Duration // maintenance and calendar exclusions
+	outages  int
+}
+
+// results of this run, written with -sla
+var slaResults = make([]slaResult, 0)
+
+// maintenances defined in Zabbix, nil until loaded
+var zabbixMaintenances []zabbix.MaintenanceElement
+
+/**
+ * Start and end of the calendar period (day, week or month) offset periods before the one containing now.
+ * The current period ends at now
+ */
+func slaPeriod(now time.Time, period string, offset int) (time.Time, time.Time) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var start, end time.Time
+	switch period {
+	case "day":
+		start = midnight.AddDate(0, 0, -offset)
+		end = start.AddDate(0, 0, 1)
+	case "week":
+		weekday := (int(now.Weekday()) + 6) % 7 // monday = 0
+		start = midnight.AddDate(0, 0, -weekday-7*offset)
+		end = start.AddDate(0, 0, 7)
+	default:
+		start = time.Date(now.Year(), now.Month()-time.Month(offset), 1, 0, 0, 0, 0, now.Location())
+		end = start.AddDate(0, 1, 0)
+	}
+	if end.After(now) {
+		end = now
+	}
+	return start, end
+}
+
+/**
+ * True if the value matches the down condition
+ */
+func isDown(value float64, condition zabbix.ConditionConfiguration) bool {
+	switch condition.Operator {
+	case "<":
+		return value < condition.Value
+	case "<=":
+		return value <= condition.Value
+	case ">":
+		return value > condition.Value
+	case ">=":
+		return value >= condition.Value
+	case "!=":
+		return value != condition.Value
+	}
+	return value == condition.Value
+}
+
+/**
+ * Integrate the up and down time in steps of resolution. A sample is valid until the next one,
+ * the time before the first sample is unknown. skip excludes points in time, e.g. maintenance.
+ * An outage interrupted by excluded time counts once.
+ */
+func availability(series []sample, from time.Time, to time.Time, resolution time.Duration, condition zabbix.ConditionConfiguration, skip func(time.Time) bool) slaResult {
+	result := slaResult{from: from, to: to}
+	if len(series) == 0 {
+		return result
+	}
+	next := 0
+	current := -1
+	wasDown := false
+	for t := from; t.Before(to); t = t.Add(resolution) {
+		for next < len(series) && !series[next].time.After(t) {
+			current = next
+			next++
+		}
+		if current < 0 {
+			continue
+		}
+		if skip(t) {
+			result.excluded += resolution
+			continue
+		}
+		if isDown(series[current].value, condition) {
+			if !wasDown {
+				result.outages++
+			}
+			wasDown = true
+			result.down += resolution
+		} else {
+			wasDown = false
+			result.up += resolution
+		}
+	}
+	return result
+}
+
+/**
+ * Uptime in percent, downtime in minutes, outages and excluded minutes keyed by output type
+ */
+func (r slaResult) values() map[string]float64 {
+	uptime := 100.0
+	if r.up+r.down > 0 {
+		uptime = 100 * r.up.Seconds() / (r.up + r.down).Seconds()
+	}
+	return map[string]float64{
+		"uptime":   uptime,
+		"downtime": r.down.Minutes(),
+		"outages":  float64(r.outages),
+		"excluded": r.excluded.Minutes(),
+	}
+}
+
+/**
+ * Compute the availability of the item over the configured period
+ */
+func processSLA(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.SLA
+	windows, err := zabbix.ParseSchedule(configuration.Maintenance, configuration.Timezone)
+	if err != nil {
+		Log.Error("skipping item with invalid maintenance windows or timezone", "item", item.ItemID, "error", err)
+		return
+	}
+	location := windows.Location
+	maintenances := hostMaintenances(session, item.HostID)
+	skip := func(t time.Time) bool {
+		if len(windows.Periods) > 0 && windows.Contains(t) {
+			return true
+		}
+		for _, maintenance := range maintenances {
+			if maintenance.Contains(t, location) {
+				return true
+			}
+		}
+		return excluded(item.HostID, t)
+	}
+
+	from, to := slaPeriod(time.Now().In(location), configuration.Period, configuration.Offset)
+	series := loadSeries(session, item, from, to, "history")
+	// the value at the start of the period is the last one before it, however long ago
+	if start, found := lastValueBefore(session, item, from); found {
+		series = append([]sample{start}, series...)
+	}
+	result := availability(series, from, to, time.Minute, configuration.Down, skip)
+	result.host = hosts[item.HostID]
+	result.key = item.Key
+	slaResults = append(slaResults, result)
+
+	values := result.values()
+	Log.Info("sla", "item", item.ItemID, "from", from.Format("2006-01-02 15:04"), "to", to.Format("2006-01-02 15:04"), "values", values)
+	if result.up+result.down == 0 {
+		Log.Warn("no availability data in period", "item", item.ItemID)
+		return
+	}
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, "uptime", "downtime", "outages")
+	emitOutputs(item, outputs, to, values)
+}
+
+/**
+ * Zabbix maintenances assigned to the host or its host groups. All maintenances are loaded with the first SLA item
+ */
+func hostMaintenances(session zabbix.Session, hostID string) []zabbix.MaintenanceElement {
+	if zabbixMaintenances == nil {
+		query := session.NewMaintenanceQuery()
+		zabbixMaintenances = query.Query()
+		if zabbixMaintenances == nil {
+			zabbixMaintenances = make([]zabbix.MaintenanceElement, 0)
+		}
+	}
+	applying := make([]zabbix.MaintenanceElement, 0)
+	for _, maintenance := range zabbixMaintenances {
+		if maintenance.AppliesTo(hostID, hostGroups[hostID]) {
+			applying = append(applying, maintenance)
+		}
+	}
+	return applying
+}
+
+/**
+ * Most recent numeric value of an item before t
+ */
+func lastValueBefore(session zabbix.Session, item zabbix.ItemResponseElement, t time.Time) (sample, bool) {
+	query := session.NewHistoryQuery()
+	query.ValueType = item.ValueType
+	query.Items = []string{item.ItemID}
+	query.To = t.Unix() - 1
+	query.Limit = 1
+	if _, found := itemPreprocessing[item.ItemID]; found {
+		// rate and delta need the previous value
+		query.Limit = 2
+	}
+	series := historySamples(preprocessHistory(item.ItemID, query.Query()))
+	if len(series) == 0 {
+		return sample{}, false
+	}
+	return series[0], true
+}
+
+/**
+ * SLA results of this run as csv
+ */
+func slaReport(results []slaResult) []byte {
+	var b bytes.Buffer
+	writer := csv.NewWriter(&b)
+	writer.Write([]string{"host", "key", "from", "to", "uptime", "downtime", "outages", "excluded"})
+	for _, result := range results {
+		values := result.values()
+		writer.Write([]string{result.host, result.key, result.from.Format(time.RFC3339), result.to.Format(time.RFC3339),
+			strconv.FormatFloat(values["uptime"], 'f', 4, 64), strconv.FormatFloat(values["downtime"], 'f', 0, 64),
+			strconv.Itoa(result.outages), strconv.FormatFloat(values["excluded"], 'f', 0, 64)})
+	}
+	writer.Flush()
+	return b.Bytes()
+}
+
+func writeSLAReport(filename string) error {
+	Log.Info("writing sla report", "file", filename, "items", len(slaResults))
+	return ioutil.WriteFile(filename, slaReport(slaResults), 0644)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSLAPeriod(t *testing.T) {
+	// Wednesday
+	now := time.Date(2019, 3, 13, 15, 30, 0, 0, time.UTC)
+	from, to := slaPeriod(now, "month", 0)
+	assert.Equal(t, time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, now, to)
+	from, to = slaPeriod(now, "month", 3)
+	assert.Equal(t, time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), to)
+	from, to = slaPeriod(now, "week", 1)
+	assert.Equal(t, time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2019, 3, 11, 0, 0, 0, 0, time.UTC), to)
+	from, _ = slaPeriod(now, "day", 1)
+	assert.Equal(t, time.Date(2019, 3, 12, 0, 0, 0, 0, time.UTC), from)
+
+	// 23:30 UTC is already the next day in Zurich
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	assert.Nil(t, err)
+	late := time.Date(2019, 3, 31, 23, 30, 0, 0, time.UTC)
+	from, to = slaPeriod(late.In(zurich), "month", 1)
+	assert.Equal(t, time.Date(2019, 3, 1, 0, 0, 0, 0, zurich), from)
+	assert.Equal(t, time.Date(2019, 4, 1, 0, 0, 0, 0, zurich), to)
+	assert.Equal(t, time.Date(2019, 3, 31, 22, 0, 0, 0, time.UTC), to.UTC())
+}
+
+func TestIsDown(t *testing.T) {
+	assert.True(t, isDown(0, zabbix.ConditionConfiguration{}))
+	assert.False(t, isDown(1, zabbix.ConditionConfiguration{}))
+	assert.True(t, isDown(0.5, zabbix.ConditionConfiguration{Operator: "<", Value: 1}))
+	assert.True(t, isDown(500, zabbix.ConditionConfiguration{Operator: ">=", Value: 500}))
+	assert.False(t, isDown(1, zabbix.ConditionConfiguration{Operator: "!=", Value: 1}))
+}
+
+func TestAvailability(t *testing.T) {
+	from := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	series := []sample{
+		{time: from.Add(-time.Minute), value: 1},
+		{time: from.Add(1 * time.Hour), value: 0},
+		{time: from.Add(2 * time.Hour), value: 1},
+		{time: from.Add(5 * time.Hour), value: 0},
+		{time: from.Add(7 * time.Hour), value: 1},
+	}
+	never := func(time.Time) bool { return false }
+	result := availability(series, from, to, time.Minute, zabbix.ConditionConfiguration{}, never)
+	assert.Equal(t, 3*time.Hour, result.down)
+	assert.Equal(t, 7*time.Hour, result.up)
+	assert.Equal(t, 2, result.outages)
+	assert.InDelta(t, 70, result.values()["uptime"], 1e-9)
+	assert.Equal(t, 180.0, result.values()["downtime"])
+
+	// maintenance during the second outage
+	maintenance := func(t time.Time) bool { return t.Hour() == 5 }
+	result = availability(series, from, to, time.Minute, zabbix.ConditionConfiguration{}, maintenance)
+	assert.Equal(t, 2*time.Hour, result.down)
+	assert.Equal(t, time.Hour, result.excluded)
+	assert.Equal(t, 2, result.outages)
+
+	// unknown before the first sample
+	result = availability(series[2:], from, to, time.Minute, zabbix.ConditionConfiguration{}, never)
+	assert.Equal(t, 8*time.Hour, result.up+result.down)
+
+	assert.Equal(t, 100.0, availability(nil, from, to, time.Minute, zabbix.ConditionConfiguration{}, never).values()["uptime"])
+}
+
+func TestHostMaintenances(t *testing.T) {
+	defer func(groups map[string][]string) { hostGroups = groups }(hostGroups)
+	hostGroups = map[string][]string{"1": {"Web"}, "2": {"Linux servers"}}
+	defer func() { zabbixMaintenances = nil }()
+	calls := 0
+	session := fakeAPI(t, func(method string, params map[string]interface{}) interface{} {
+		calls++
+		assert.Equal(t, "maintenance.get", method)
+		assert.Equal(t, "extend", params["selectTimeperiods"])
+		return []map[string]interface{}{
+			{"maintenanceid": "1", "active_since": "0", "active_till": "2000000000", "hosts": []map[string]string{{"hostid": "1"}}},
+			{"maintenanceid": "2", "active_since": "0", "active_till": "2000000000", "groups": []map[string]string{{"name": "Linux servers"}}},
+		}
+	})
+	assert.Equal(t, "1", hostMaintenances(session, "1")[0].MaintenanceID)
+	assert.Equal(t, "2", hostMaintenances(session, "2")[0].MaintenanceID)
+	assert.Empty(t, hostMaintenances(session, "3"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestSLAReport(t *testing.T) {
+	from := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	report := string(slaReport([]slaResult{{host: "web01", key: `net.tcp.service[http,,"80"]`, from: from, to: from.Add(time.Hour), up: 54 * time.Minute, down: 6 * time.Minute, outages: 1}}))
+	lines := strings.Split(strings.TrimSpace(report), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, `web01,"net.tcp.service[http,,""80""]",2019-03-01T00:00:00Z,2019-03-01T01:00:00Z,90.0000,6,1,0`, lines[1])
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// Algorithm state by key, kept between runs
+var processorState = make(map[string]json.RawMessage)
+
+/**
+ * Read the state file. A missing file is an empty state
+ */
+func loadState(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		Log.Info("no state file found, starting without state", "file", filename)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &processorState)
+}
+
+func saveState(filename string) error {
+	data, err := json.MarshalIndent(processorState, "", "  ")
+	if err != nil {
+		return err
+	}
+	Log.Info("writing state", "file", filename, "entries", len(processorState))
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+/**
+ * Decode the state stored under key into value. Returns false if there is no usable state
+ */
+func getState(key string, value interface{}) bool {
+	data, found := processorState[key]
+	if !found {
+		return false
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		Log.Warn("ignoring invalid state", "key", key, "error", err)
+		return false
+	}
+	return true
+}
+
+func putState(key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		Log.Warn("cannot store state", "key", key, "error", err)
+		return
+	}
+	processorState[key] = data
+}
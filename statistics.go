@@ -0,0 +1,130 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"math"
+	"sort"
+)
+
+/**
+ * Average without the smallest and the largest value if there are more than two values
+ */
+func average(values []float64) float64 {
+	return mean(trimmed(values))
+}
+
+func mean(values []float64) float64 {
+	sum := float64(0)
+
+	for _, value := range values {
+		sum = sum + value
+	}
+	return sum / float64(len(values))
+}
+
+/**
+ * Sample standard deviation of all values. Not trimmed like average, which would leave a single value of three
+ */
+func standardDeviation(values []float64) float64 {
+	if len(values) < 2 {
+		return math.NaN()
+	}
+	center := mean(values)
+	sum := float64(0)
+	for _, value := range values {
+		sum += (value - center) * (value - center)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+/**
+ * Sorted copy of the values without the smallest and the largest value
+ */
+func trimmed(values []float64) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if len(sorted) > 2 {
+		sorted = sorted[1 : len(sorted)-1]
+	}
+	return sorted
+}
+
+/**
+ * Deviation of the current value from the historic samples, keyed by output type
+ */
+func deviations(current float64, samples []float64) map[string]float64 {
+	historic := average(samples)
+	values := map[string]float64{
+		"absolute": current - historic,
+		"percent":  math.NaN(),
+		"ratio":    math.NaN(),
+		"zscore":   math.NaN(),
+	}
+	if historic != 0 {
+		values["percent"] = 100 * (current - historic) / math.Abs(historic)
+		values["ratio"] = current / historic
+	}
+	if deviation := standardDeviation(samples); deviation > 0 {
+		values["zscore"] = (current - historic) / deviation
+	}
+	return values
+}
+
+/**
+ * Percentile p (0..100) with linear interpolation between the closest ranks
+ */
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower < 0 {
+		return sorted[0]
+	}
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+/**
+ * Expected value and tolerance band of the historic samples, keyed by output type
+ */
+func band(samples []float64, configuration zabbix.BandConfiguration) map[string]float64 {
+	expected := average(samples)
+	values := map[string]float64{"expected": expected, "lower": math.NaN(), "upper": math.NaN()}
+	if len(samples) == 0 {
+		return values
+	}
+	switch configuration.Method {
+	case "", "minmax":
+		values["lower"] = percentile(samples, 0)
+		values["upper"] = percentile(samples, 100)
+	case "stddev":
+		k := configuration.K
+		if k == 0 {
+			k = 2
+		}
+		deviation := standardDeviation(samples)
+		values["lower"] = expected - k*deviation
+		values["upper"] = expected + k*deviation
+	case "percentile":
+		lower, upper := configuration.Lower, configuration.Upper
+		if upper == 0 {
+			// bounds not configured
+			if lower == 0 {
+				lower = 5
+			}
+			upper = 95
+		}
+		values["lower"] = percentile(samples, lower)
+		values["upper"] = percentile(samples, upper)
+	default:
+		Log.Warn("unknown band method", "method", configuration.Method)
+	}
+	return values
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestAverageDropsExtremes(t *testing.T) {
+	assert.Equal(t, 3.0, average([]float64{100, 2, 3, 4, -50}))
+	assert.Equal(t, 1.5, average([]float64{1, 2}))
+}
+
+func TestAverageKeepsInputOrder(t *testing.T) {
+	values := []float64{3, 1, 2}
+	average(values)
+	assert.Equal(t, []float64{3, 1, 2}, values)
+}
+
+func TestDeviations(t *testing.T) {
+	values := deviations(15, []float64{0, 8, 10, 12, 100})
+	assert.Equal(t, 5.0, values["absolute"])
+	assert.Equal(t, 50.0, values["percent"])
+	assert.Equal(t, 1.5, values["ratio"])
+	assert.InDelta(t, 5/math.Sqrt(1732), values["zscore"], 1e-9)
+}
+
+func TestDeviationsOfThreeWeeks(t *testing.T) {
+	assert.Equal(t, 2.0, standardDeviation([]float64{8, 10, 12}))
+	values := deviations(14, []float64{8, 10, 12})
+	assert.Equal(t, 4.0, values["absolute"])
+	assert.Equal(t, 2.0, values["zscore"])
+	assert.True(t, math.IsNaN(standardDeviation([]float64{8})))
+}
+
+func TestDeviationsWithoutSpread(t *testing.T) {
+	values := deviations(5, []float64{0, 0, 0})
+	assert.Equal(t, 5.0, values["absolute"])
+	assert.True(t, math.IsNaN(values["percent"]))
+	assert.True(t, math.IsNaN(values["ratio"]))
+	assert.True(t, math.IsNaN(values["zscore"]))
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{4, 1, 3, 2, 5}
+	assert.Equal(t, 1.0, percentile(values, 0))
+	assert.Equal(t, 3.0, percentile(values, 50))
+	assert.Equal(t, 4.6, percentile(values, 90))
+	assert.Equal(t, 5.0, percentile(values, 100))
+	assert.True(t, math.IsNaN(percentile(nil, 50)))
+}
+
+func TestBand(t *testing.T) {
+	samples := []float64{0, 8, 10, 12, 100}
+
+	values := band(samples, zabbix.BandConfiguration{})
+	assert.Equal(t, 10.0, values["expected"])
+	assert.Equal(t, 0.0, values["lower"])
+	assert.Equal(t, 100.0, values["upper"])
+
+	values = band(samples, zabbix.BandConfiguration{Method: "stddev", K: 1})
+	assert.InDelta(t, 10-math.Sqrt(1732), values["lower"], 1e-9)
+	assert.InDelta(t, 10+math.Sqrt(1732), values["upper"], 1e-9)
+
+	values = band(samples, zabbix.BandConfiguration{Method: "percentile", Lower: 25, Upper: 75})
+	assert.Equal(t, 8.0, values["lower"])
+	assert.Equal(t, 12.0, values["upper"])
+
+	values = band(samples, zabbix.BandConfiguration{Method: "percentile"})
+	assert.InDelta(t, 1.6, values["lower"], 1e-9)
+	assert.InDelta(t, 82.4, values["upper"], 1e-9)
+}
+
+func TestBandOfThreeWeeks(t *testing.T) {
+	values := band([]float64{8, 10, 12}, zabbix.BandConfiguration{Method: "stddev"})
+	assert.Equal(t, 6.0, values["lower"])
+	assert.Equal(t, 14.0, values["upper"])
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"time"
+)
+
+/**
+ * Series split into trend, seasonal and remainder. value = trend + seasonal + remainder
+ */
+type decomposition struct {
+	Clock     []int64   `json:"clock"`
+	Value     []float64 `json:"value"`
+	Trend     []float64 `json:"trend"`
+	Seasonal  []float64 `json:"seasonal"`
+	Remainder []float64 `json:"remainder"`
+}
+
+/**
+ * STL decomposition (Cleveland et al. 1990) without the robustness iterations.
+ * period is the number of buckets per season.
+ */
+func decompose(series []float64, period int) decomposition {
+	n := len(series)
+	seasonalSpan := 7
+	lowpassSpan := nextOdd(float64(period))
+	trendSpan := nextOdd(1.5 * float64(period) / (1 - 1.5/float64(seasonalSpan)))
+
+	trend := make([]float64, n)
+	seasonal := make([]float64, n)
+	for pass := 0; pass < 2; pass++ {
+		// cycle-subseries smoothing, extended by one season on each side
+		cycle := make([]float64, n+2*period)
+		for position := 0; position < period; position++ {
+			subseries := make([]float64, 0, n/period+1)
+			for i := position; i < n; i += period {
+				subseries = append(subseries, series[i]-trend[i])
+			}
+			for j := -1; j <= len(subseries); j++ {
+				index := position + j*period + period
+				if index >= 0 && index < len(cycle) {
+					cycle[index] = loessAt(subseries, seasonalSpan, float64(j))
+				}
+			}
+		}
+
+		// remove the low frequency part from the seasonal component
+		lowpass := movingAverage(movingAverage(movingAverage(cycle, period), period), 3)
+		for i := 0; i < n; i++ {
+			seasonal[i] = cycle[i+period] - loessAt(lowpass, lowpassSpan, float64(i))
+		}
+
+		deseasonalized := make([]float64, n)
+		for i := range series {
+			deseasonalized[i] = series[i] - seasonal[i]
+		}
+		for i := range trend {
+			trend[i] = loessAt(deseasonalized, trendSpan, float64(i))
+		}
+	}
+
+	result := decomposition{Value: series, Trend: trend, Seasonal: seasonal, Remainder: make([]float64, n)}
+	for i := range series {
+		result.Remainder[i] = series[i] - trend[i] - seasonal[i]
+	}
+	return result
+}
+
+/**
+ * Locally weighted linear regression of values (at positions 0..n-1) evaluated at x,
+ * using the span nearest values with tricube weights
+ */
+func loessAt(values []float64, span int, x float64) float64 {
+	n := len(values)
+	if n == 0 {
+		return math.NaN()
+	}
+	if n == 1 {
+		return values[0]
+	}
+	left := int(math.Round(x)) - span/2
+	if left > n-span {
+		left = n - span
+	}
+	if left < 0 {
+		left = 0
+	}
+	right := left + span - 1
+	if right >= n {
+		right = n - 1
+	}
+	h := math.Max(x-float64(left), float64(right)-x)
+	if span > n {
+		h += float64(span-n) / 2
+	}
+	h = math.Max(h, 1) * 1.001
+
+	var sw, swx, swy, swxx, swxy float64
+	for i := left; i <= right; i++ {
+		d := math.Abs(float64(i)-x) / h
+		w := math.Pow(1-d*d*d, 3)
+		if d >= 1 {
+			w = 0
+		}
+		sw += w
+		swx += w * float64(i)
+		swy += w * values[i]
+		swxx += w * float64(i) * float64(i)
+		swxy += w * float64(i) * values[i]
+	}
+	denominator := sw*swxx - swx*swx
+	if math.Abs(denominator) < 1e-12 {
+		return swy / sw
+	}
+	slope := (sw*swxy - swx*swy) / denominator
+	return (swy-slope*swx)/sw + slope*x
+}
+
+/**
+ * Moving average with the given window. The result has len(values)-window+1 elements
+ */
+func movingAverage(values []float64, window int) []float64 {
+	if window > len(values) {
+		return []float64{mean(values)}
+	}
+	result := make([]float64, 0, len(values)-window+1)
+	sum := float64(0)
+	for i, value := range values {
+		sum += value
+		if i >= window {
+			sum -= values[i-window]
+		}
+		if i >= window-1 {
+			result = append(result, sum/float64(window))
+		}
+	}
+	return result
+}
+
+func nextOdd(value float64) int {
+	odd := int(math.Ceil(value))
+	if odd%2 == 0 {
+		odd++
+	}
+	return odd
+}
+
+/**
+ * Write the decomposition as csv or json to the directory
+ */
+func exportDecomposition(directory string, format string, name string, result decomposition) error {
+	var data []byte
+	if format == "json" {
+		var err error
+		data, err = json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+	} else {
+		format = "csv"
+		var b bytes.Buffer
+		b.WriteString("clock,value,trend,seasonal,remainder\n")
+		for i := range result.Value {
+			fmt.Fprintf(&b, "%d,%f,%f,%f,%f\n", result.Clock[i], result.Value[i], result.Trend[i], result.Seasonal[i], result.Remainder[i])
+		}
+		data = b.Bytes()
+	}
+	filename := filepath.Join(directory, name+"."+format)
+	Log.Info("writing decomposition", "file", filename)
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+/**
+ * Decompose the item history and emit the latest trend, seasonal and remainder values
+ */
+func processDecomposition(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.STL
+	season := time.Duration(configuration.Period) * time.Second
+	if season == 0 {
+		season = time.Hour * 24 * 7
+	}
+	interval := time.Duration(configuration.Interval) * time.Second
+	if interval == 0 {
+		interval = time.Hour
+	}
+
+	now := time.Now()
+	series := loadSeries(session, item, now.Add(-time.Duration(configuration.Lookback)*time.Second), now, configuration.Source)
+	if len(series) == 0 {
+		Log.Warn("skipping item due to missing data", "item", item)
+		return
+	}
+	sampler := newResampler(itemConfiguration.Resample, interval)
+	// the last bucket may be incomplete, the results are emitted at the time of the latest sample
+	latest := sampler.ceil(series[len(series)-1].time)
+	count := int(time.Duration(configuration.Lookback) * time.Second / interval)
+	buckets := skipMissing(sampler.resample(series, latest, count))
+
+	period := int(season / interval)
+	if period < 2 || len(buckets) < 2*period {
+		Log.Warn("not enough history for decomposition", "item", item.ItemID, "buckets", len(buckets), "period", period)
+		return
+	}
+
+	result := decompose(buckets, period)
+	result.Clock = make([]int64, len(buckets))
+	for i := range buckets {
+		result.Clock[i] = latest.Add(-interval * time.Duration(len(buckets)-1-i)).Unix()
+	}
+
+	if configuration.Export != "" {
+		name := fmt.Sprintf("%s_%s", hosts[item.HostID], item.ItemID)
+		if err := exportDecomposition(configuration.Export, configuration.Format, name, result); err != nil {
+			Log.Warn("failed to export decomposition", "item", item.ItemID, "error", err)
+		}
+	}
+
+	last := len(buckets) - 1
+	values := map[string]float64{"trend": result.Trend[last], "seasonal": result.Seasonal[last], "remainder": result.Remainder[last]}
+	outputs := outputsOrDefault(configuration.Outputs, itemConfiguration, "trend", "remainder")
+	emitOutputs(item, outputs, series[len(series)-1].time, values)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecompose(t *testing.T) {
+	series := make([]float64, 0, 24*14)
+	for i := 0; i < 24*14; i++ {
+		series = append(series, 50+0.05*float64(i)+10*math.Sin(2*math.Pi*float64(i)/24))
+	}
+	result := decompose(series, 24)
+	assert.Equal(t, len(series), len(result.Trend))
+	for i := 24; i < len(series)-24; i++ {
+		assert.InDelta(t, 50+0.05*float64(i), result.Trend[i], 1)
+		assert.InDelta(t, 10*math.Sin(2*math.Pi*float64(i)/24), result.Seasonal[i], 1)
+		assert.InDelta(t, series[i], result.Trend[i]+result.Seasonal[i]+result.Remainder[i], 1e-9)
+	}
+}
+
+func TestLoessReproducesLine(t *testing.T) {
+	values := []float64{1, 3, 5, 7, 9, 11}
+	assert.InDelta(t, 6, loessAt(values, 3, 2.5), 1e-9)
+	assert.InDelta(t, -1, loessAt(values, 3, -1), 1e-9)
+	assert.InDelta(t, 13, loessAt(values, 9, 6), 1e-9)
+}
+
+func TestMovingAverage(t *testing.T) {
+	assert.Equal(t, []float64{2, 3, 4}, movingAverage([]float64{1, 2, 3, 4, 5}, 3))
+}
+
+func TestExportDecomposition(t *testing.T) {
+	directory, err := ioutil.TempDir("", "stl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+
+	result := decomposition{Clock: []int64{10}, Value: []float64{3}, Trend: []float64{2}, Seasonal: []float64{0.5}, Remainder: []float64{0.5}}
+	assert.Nil(t, exportDecomposition(directory, "csv", "host_1", result))
+	data, err := ioutil.ReadFile(filepath.Join(directory, "host_1.csv"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(string(data), "10,3.000000,2.000000,0.500000,0.500000\n"))
+
+	assert.Nil(t, exportDecomposition(directory, "json", "host_1", result))
+	data, err = ioutil.ReadFile(filepath.Join(directory, "host_1.json"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "\"remainder\"")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"time"
+)
+
+/**
+ * Latest value of a character or text item compared with its usual value
+ */
+type textChange struct {
+	current  string
+	common   string // most frequent value before the current one
+	previous string // latest value before the current one that differs from it. empty if none
+	differs  bool
+}
+
+/**
+ * Compare the newest value with the older ones. values are newest first like the history API returns them.
+ * Ties of the most common value are resolved in favour of the more recent value.
+ */
+func compareText(values []zabbix.HistoryValue) (textChange, bool) {
+	if len(values) < 2 {
+		return textChange{}, false
+	}
+	change := textChange{current: values[0].Value}
+	counts := make(map[string]int)
+	best := 0
+	for _, value := range values[1:] {
+		counts[value.Value]++
+		if counts[value.Value] > best {
+			best = counts[value.Value]
+		}
+		if change.previous == "" && value.Value != change.current {
+			change.previous = value.Value
+		}
+	}
+	for _, value := range values[1:] {
+		if counts[value.Value] == best {
+			change.common = value.Value
+			break
+		}
+	}
+	change.differs = change.current != change.common
+	return change, true
+}
+
+/**
+ * Emit 1/0 if the latest value differs from the most common value of the past weeks, and the values as text
+ */
+func processTextChange(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	configuration := itemConfiguration.TextChange
+	now := time.Now()
+	values := loadValues(session, item, now.Add(-time.Hour*24*7*time.Duration(configuration.Weeks)), now.Add(time.Second))
+	change, found := compareText(values)
+	if !found {
+		Log.Warn("skipping item due to missing data", "item", item.ItemID, "values", len(values))
+		return
+	}
+	Log.Info("text change", "item", item.ItemID, "current", change.current, "common", change.common, "differs", change.differs)
+
+	differs := 0.0
+	if change.differs {
+		differs = 1
+	}
+	texts := map[string]string{"current": change.current, "common": change.common, "previous": change.previous}
+	timestamp := time.Unix(values[0].Clock, values[0].Nano)
+	numeric := make([]zabbix.OutputConfiguration, 0)
+	for _, output := range outputsOrDefault(configuration.Outputs, itemConfiguration, "differs", "current", "previous") {
+		text, isText := texts[output.Type]
+		switch {
+		case !isText:
+			numeric = append(numeric, output)
+		case output.Type == "previous" && text == "":
+			Log.Debug("no previous value", "item", item.ItemID)
+		default:
+			if key, ok := derivedKey(item.Key, output.Postfix, output.Key); ok {
+				addSenderText(hosts[item.HostID], key, timestamp, text)
+			}
+		}
+	}
+	emitOutputs(item, numeric, timestamp, map[string]float64{"differs": differs})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func history(values ...string) []zabbix.HistoryValue {
+	result := make([]zabbix.HistoryValue, len(values))
+	for i, value := range values {
+		result[i] = zabbix.HistoryValue{Value: value, Clock: int64(1000 - i)}
+	}
+	return result
+}
+
+func TestCompareText(t *testing.T) {
+	change, found := compareText(history("2.1", "2.0", "2.0", "1.9"))
+	assert.True(t, found)
+	assert.Equal(t, textChange{current: "2.1", common: "2.0", previous: "2.0", differs: true}, change)
+
+	change, _ = compareText(history("2.0", "2.0", "1.9", "2.0"))
+	assert.Equal(t, textChange{current: "2.0", common: "2.0", previous: "1.9", differs: false}, change)
+
+	// tie: the more recent value is the common one
+	change, _ = compareText(history("b", "b", "a", "a", "b"))
+	assert.Equal(t, "b", change.common)
+	assert.False(t, change.differs)
+
+	change, _ = compareText(history("ok", "ok"))
+	assert.Equal(t, "", change.previous)
+
+	_, found = compareText(history("only"))
+	assert.False(t, found)
+}
+
+func TestSenderQuote(t *testing.T) {
+	assert.Equal(t, "2.0", senderQuote("2.0"))
+	assert.Equal(t, `""`, senderQuote(""))
+	assert.Equal(t, `"Linux 4.19 \"stable\""`, senderQuote(`Linux 4.19 "stable"`))
+	assert.Equal(t, `"C:\\Windows two lines"`, senderQuote("C:\\Windows\ntwo lines"))
+}
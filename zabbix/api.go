@@ -95,6 +95,11 @@ type HistoryValue struct {
 	Item  string `json:"itemid"`
 	Clock int64  `json:"clock,string"` // seconds since epoch
 	Nano  int64  `json:"ns,string"`    // nanoseconds
+
+	// log items (value type 2) only
+	Source     string `json:"source,omitempty"`
+	Severity   int    `json:"severity,string,omitempty"`
+	LogEventID int    `json:"logeventid,string,omitempty"`
 }
 
 /**
@@ -189,12 +194,15 @@ type itemQueryResponse struct {
  */
 type HostQuery struct {
 	TemplateIDs            []string            `json:"templateids,omitempty"` // search for specific template id's
-	Output                 string              `json:"output"`                // extend | count
+	HostIDs                []string            `json:"hostids,omitempty"`
+	Output                 string              `json:"output"` // extend | count
 	Filter                 map[string][]string `json:"filter,omitempty"`
 	Search                 map[string][]string `json:"search,omitempty"`
 	SearchWildcardsEnabled bool                `json:"searchWildcardsEnabled"`
-	IncludeTemplates       bool                `json:"templated_hosts"` // Return both hosts and templates.
-	IncludeMonitored       bool                `json:"monitored_hosts"` // Return only monitored hosts.
+	IncludeTemplates       bool                `json:"templated_hosts"`                 // Return both hosts and templates.
+	IncludeMonitored       bool                `json:"monitored_hosts"`                 // Return only monitored hosts.
+	SelectGroups           string              `json:"selectGroups,omitempty"`          // extend to return the host groups
+	SelectParentTemplates  string              `json:"selectParentTemplates,omitempty"` // extend to return the linked templates
 
 	SortField []string
 
@@ -214,6 +222,54 @@ type HostResponseElement struct {
 	Name       string
 	Status     string
 	Available  string
+	Groups     []HostGroupElement
+	Templates  []TemplateResponseItem `json:"parentTemplates"`
+}
+
+type HostGroupElement struct {
+	GroupID string `json:"groupid"`
+	Name    string
+}
+
+/**
+* Refer to https://www.zabbix.com/documentation/4.0/manual/api/reference/usermacro/get
+ */
+type UserMacroQuery struct {
+	HostIDs []string `json:"hostids,omitempty"`
+	Output  string   `json:"output"`                // extend | count
+	Global  bool     `json:"globalmacro,omitempty"` // return global macros instead of host macros
+
+	session Session
+}
+
+type userMacroQueryResponse struct {
+	Encoding string                     `json:"jsonrpc"` // "2.0"
+	Elements []UserMacroResponseElement `json:"result"`  // macros
+}
+
+type UserMacroResponseElement struct {
+	HostMacroID string `json:"hostmacroid"`
+	HostID      string `json:"hostid"`
+	Macro       string `json:"macro"` // e.g. {$DISK.LIMIT}
+	Value       string `json:"value"`
+}
+
+/**
+* Refer to https://www.zabbix.com/documentation/4.0/manual/api/reference/maintenance/get
+ */
+type MaintenanceQuery struct {
+	HostIDs           []string `json:"hostids,omitempty"`
+	Output            string   `json:"output"`            // extend | count
+	SelectHosts       string   `json:"selectHosts"`       // extend
+	SelectGroups      string   `json:"selectGroups"`      // extend
+	SelectTimeperiods string   `json:"selectTimeperiods"` // extend
+
+	session Session
+}
+
+type maintenanceQueryResponse struct {
+	Encoding string               `json:"jsonrpc"` // "2.0"
+	Elements []MaintenanceElement `json:"result"`  // maintenances
 }
 
 func init() {
@@ -326,6 +382,7 @@ func (s *Session) NewHostQuery(templateids []string, filter map[string][]string,
 	// ignore templates by default
 	q.IncludeTemplates = false
 	q.IncludeMonitored = false
+	q.SelectGroups = "extend"
 	q.SortField = []string{"hostid"}
 	return q
 }
@@ -343,6 +400,42 @@ func (q *HostQuery) Query() []HostResponseElement {
 	return response.Elements
 }
 
+func (s *Session) NewUserMacroQuery(hostids []string) UserMacroQuery {
+	q := UserMacroQuery{Output: "extend", session: *s}
+	q.HostIDs = hostids
+	return q
+}
+
+func (q *UserMacroQuery) Query() []UserMacroResponseElement {
+	response := userMacroQueryResponse{}
+	req := Request{session: q.session, request: q, response: &response, method: "usermacro.get"}
+	err := req.query()
+	if err != nil {
+		Log.Error("failed to read user macros", "error", err)
+		return nil
+	}
+	Log.Debug("loaded", logging.Ctx{"count": len(response.Elements)})
+
+	return response.Elements
+}
+
+func (s *Session) NewMaintenanceQuery() MaintenanceQuery {
+	return MaintenanceQuery{Output: "extend", SelectHosts: "extend", SelectGroups: "extend", SelectTimeperiods: "extend", session: *s}
+}
+
+func (q *MaintenanceQuery) Query() []MaintenanceElement {
+	response := maintenanceQueryResponse{}
+	req := Request{session: q.session, request: q, response: &response, method: "maintenance.get"}
+	err := req.query()
+	if err != nil {
+		Log.Error("failed to read maintenances", "error", err)
+		return nil
+	}
+	Log.Debug("loaded", logging.Ctx{"count": len(response.Elements)})
+
+	return response.Elements
+}
+
 func (query *Request) query() error {
 	uri := query.session.URL
 	request := request{Encoding: "2.0", Method: query.method, Params: query.request, Id: requestEnumerator}
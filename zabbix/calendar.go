@@ -0,0 +1,225 @@
+package zabbix
+
+/**
+ * Calendars of dates excluded from baselines, e.g. public holidays.
+ * Loaded from the configuration and iCalendar files (RFC 5545, VEVENT with DTSTART, DTEND and yearly RRULE)
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+type Calendar struct {
+	Name       string
+	Groups     []string // host groups using the calendar. all hosts if empty
+	Exclusions []Exclusion
+}
+
+// Excluded time range [From, To)
+type Exclusion struct {
+	From   time.Time
+	To     time.Time
+	Yearly bool // repeats every year from From on
+}
+
+/**
+ * Build a calendar from its configuration, reading the iCalendar file if configured
+ */
+func LoadCalendar(configuration CalendarConfiguration) (Calendar, error) {
+	calendar := Calendar{Name: configuration.Name, Groups: configuration.Groups}
+	for _, date := range configuration.Dates {
+		from, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			return Calendar{}, fmt.Errorf("invalid date %q in calendar %s", date, configuration.Name)
+		}
+		calendar.Exclusions = append(calendar.Exclusions, Exclusion{From: from, To: from.AddDate(0, 0, 1)})
+	}
+	for _, period := range configuration.Ranges {
+		from, _, err := parseCalendarTime(period.From)
+		if err != nil {
+			return Calendar{}, fmt.Errorf("invalid range start %q in calendar %s", period.From, configuration.Name)
+		}
+		to, date, err := parseCalendarTime(period.To)
+		if err != nil {
+			return Calendar{}, fmt.Errorf("invalid range end %q in calendar %s", period.To, configuration.Name)
+		}
+		if date {
+			// a date includes the whole day
+			to = to.AddDate(0, 0, 1)
+		}
+		if !to.After(from) {
+			return Calendar{}, fmt.Errorf("empty range %s - %s in calendar %s", period.From, period.To, configuration.Name)
+		}
+		calendar.Exclusions = append(calendar.Exclusions, Exclusion{From: from, To: to})
+	}
+	if configuration.File != "" {
+		data, err := ioutil.ReadFile(configuration.File)
+		if err != nil {
+			return Calendar{}, err
+		}
+		exclusions, err := ParseICalendar(data)
+		if err != nil {
+			return Calendar{}, fmt.Errorf("%s: %v", configuration.File, err)
+		}
+		calendar.Exclusions = append(calendar.Exclusions, exclusions...)
+	}
+	return calendar, nil
+}
+
+// date or date with time of day, returns true for a date
+func parseCalendarTime(text string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04", text, time.Local); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", text, time.Local)
+	return t, true, err
+}
+
+/**
+ * Events of an iCalendar file as exclusions
+ */
+func ParseICalendar(data []byte) ([]Exclusion, error) {
+	exclusions := make([]Exclusion, 0)
+	var event *Exclusion
+	var date bool
+	for _, line := range unfoldICalendar(data) {
+		name, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			name, value = line[:i], line[i+1:]
+		}
+		parameters := strings.Split(name, ";")
+		name = strings.ToUpper(parameters[0])
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &Exclusion{}
+		case name == "END" && value == "VEVENT":
+			if event == nil || event.From.IsZero() {
+				return nil, fmt.Errorf("event without DTSTART")
+			}
+			if event.To.IsZero() {
+				// without end an all day event lasts one day, a point in time is excluded for a second
+				if date {
+					event.To = event.From.AddDate(0, 0, 1)
+				} else {
+					event.To = event.From.Add(time.Second)
+				}
+			}
+			exclusions = append(exclusions, *event)
+			event = nil
+		case event == nil:
+			continue
+		case name == "DTSTART" || name == "DTEND":
+			t, isDate, err := parseICalendarTime(value, parameters[1:])
+			if err != nil {
+				return nil, err
+			}
+			if name == "DTSTART" {
+				event.From, date = t, isDate
+			} else {
+				event.To = t
+			}
+		case name == "RRULE":
+			if strings.Contains(strings.ToUpper(value), "FREQ=YEARLY") {
+				event.Yearly = true
+			} else {
+				Log.Warn("ignoring unsupported recurrence rule, using the first occurrence only", "rule", value)
+			}
+		}
+	}
+	return exclusions, nil
+}
+
+// lines with continuation lines (starting with a space or tab) joined
+func unfoldICalendar(data []byte) []string {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+		} else if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// DATE (20241225), DATE-TIME in UTC (20241225T100000Z), with TZID parameter or local time
+func parseICalendarTime(value string, parameters []string) (time.Time, bool, error) {
+	if len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	location := time.Local
+	for _, parameter := range parameters {
+		if strings.HasPrefix(strings.ToUpper(parameter), "TZID=") {
+			if l, err := time.LoadLocation(strings.Trim(parameter[5:], "\"")); err == nil {
+				location = l
+			}
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	return t, false, err
+}
+
+/**
+ * True if t is inside the exclusion or, for yearly exclusions, one of its repetitions
+ */
+func (e Exclusion) Contains(t time.Time) bool {
+	if !e.Yearly {
+		return !t.Before(e.From) && t.Before(e.To)
+	}
+	if t.Before(e.From) {
+		return false
+	}
+	// the repetition starting in the year of t or the year before (ranges over new year)
+	for _, year := range []int{t.Year() - 1, t.Year()} {
+		offset := year - e.From.Year()
+		if offset < 0 {
+			continue
+		}
+		if !t.Before(e.From.AddDate(offset, 0, 0)) && t.Before(e.To.AddDate(offset, 0, 0)) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * True if any exclusion of the calendar contains t
+ */
+func (c Calendar) Excludes(t time.Time) bool {
+	for _, exclusion := range c.Exclusions {
+		if exclusion.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * True if the calendar has no groups or one of the groups is in groups
+ */
+func (c Calendar) AppliesTo(groups []string) bool {
+	if len(c.Groups) == 0 {
+		return true
+	}
+	for _, group := range c.Groups {
+		for _, hostGroup := range groups {
+			if group == hostGroup {
+				return true
+			}
+		}
+	}
+	return false
+}
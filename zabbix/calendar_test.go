@@ -0,0 +1,71 @@
+package zabbix
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.Local)
+}
+
+func TestParseICalendar(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Maintenance\r\n window\r\nDTSTART:20200301T080000Z\r\nDTEND:20200301T100000Z\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\nDTSTART;VALUE=DATE:20200501\nRRULE:FREQ=YEARLY\nEND:VEVENT\nEND:VCALENDAR\n"
+	exclusions, err := ParseICalendar([]byte(data))
+	assert.Nil(t, err)
+	assert.Len(t, exclusions, 2)
+	assert.Equal(t, time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC), exclusions[0].From.UTC())
+	assert.Equal(t, 2*time.Hour, exclusions[0].To.Sub(exclusions[0].From))
+	assert.False(t, exclusions[0].Yearly)
+	assert.Equal(t, date(2020, 5, 2, 0), exclusions[1].To)
+	assert.True(t, exclusions[1].Yearly)
+
+	_, err = ParseICalendar([]byte("BEGIN:VEVENT\nSUMMARY:no start\nEND:VEVENT\n"))
+	assert.NotNil(t, err)
+	_, err = ParseICalendar([]byte("BEGIN:VEVENT\nDTSTART:2020\nEND:VEVENT\n"))
+	assert.NotNil(t, err)
+}
+
+func TestExclusionContains(t *testing.T) {
+	once := Exclusion{From: date(2020, 3, 1, 8), To: date(2020, 3, 1, 10)}
+	assert.True(t, once.Contains(date(2020, 3, 1, 8)))
+	assert.False(t, once.Contains(date(2020, 3, 1, 10)))
+	assert.False(t, once.Contains(date(2021, 3, 1, 9)))
+
+	newYear := Exclusion{From: date(2018, 12, 31, 0), To: date(2019, 1, 2, 0), Yearly: true}
+	assert.True(t, newYear.Contains(date(2022, 12, 31, 12)))
+	assert.True(t, newYear.Contains(date(2023, 1, 1, 12)))
+	assert.False(t, newYear.Contains(date(2023, 1, 2, 12)))
+	assert.False(t, newYear.Contains(date(2017, 12, 31, 12)))
+}
+
+func TestLoadCalendar(t *testing.T) {
+	configuration := CalendarConfiguration{
+		Name:   "test",
+		Groups: []string{"Web servers"},
+		File:   "../conf/holidays.ics",
+		Dates:  []string{"2020-04-10"},
+		Ranges: []CalendarRangeConfiguration{{From: "2020-06-01 12:00", To: "2020-06-02"}},
+	}
+	calendar, err := LoadCalendar(configuration)
+	assert.Nil(t, err)
+	assert.Len(t, calendar.Exclusions, 4)
+	assert.True(t, calendar.Excludes(date(2020, 4, 10, 23)))
+	assert.False(t, calendar.Excludes(date(2020, 6, 1, 11)))
+	assert.True(t, calendar.Excludes(date(2020, 6, 2, 23)))
+	assert.True(t, calendar.Excludes(date(2024, 12, 25, 9)))
+	assert.False(t, calendar.Excludes(date(2024, 12, 27, 9)))
+
+	assert.True(t, calendar.AppliesTo([]string{"Linux servers", "Web servers"}))
+	assert.False(t, calendar.AppliesTo(nil))
+	assert.True(t, Calendar{}.AppliesTo(nil))
+
+	_, err = LoadCalendar(CalendarConfiguration{Dates: []string{"24.12.2020"}})
+	assert.NotNil(t, err)
+	_, err = LoadCalendar(CalendarConfiguration{Ranges: []CalendarRangeConfiguration{{From: "2020-06-02", To: "2020-06-01"}}})
+	assert.NotNil(t, err)
+	_, err = LoadCalendar(CalendarConfiguration{File: "missing.ics"})
+	assert.NotNil(t, err)
+}
@@ -29,6 +29,91 @@ type Configuration struct {
 
 	// Filter items on found hosts
 	Items []ItemConfiguration `yaml:"items"`
+
+	// Calculated items per host, evaluated after all items
+	Formulas []FormulaConfiguration `yaml:"formulas"`
+
+	// Algorithm state kept between runs
+	State StateConfiguration `yaml:"state"`
+
+	// Dates excluded from baselines
+	Calendars []CalendarConfiguration `yaml:"calendars"`
+
+	// Host score from the deviations of all items, after the items are processed
+	Health HealthConfiguration `yaml:"health"`
+
+	// Ranked pastweeks deviations (-report)
+	Report ReportConfiguration `yaml:"report"`
+
+	// Correlation analysis (-correlate)
+	Correlation CorrelationConfiguration `yaml:"correlation"`
+}
+
+// Combination of the normalized deviations (pastweeks deviations in standard deviations, emitted ewma and peers score outputs) of the items of a host
+type HealthConfiguration struct {
+	Key         string                      // item key of the score (0..100, 100 is healthy). enables the scoring
+	Contributor string                      // item key of the text item naming the worst item key. optional
+	Cap         float64                     // largest counted absolute deviation of an item. defaults to 5
+	Weights     []HealthWeightConfiguration // the first matching entry applies. weight 1 otherwise
+}
+
+type HealthWeightConfiguration struct {
+	Key    string  // item key, * is a wildcard
+	Weight float64 // 0 ignores the item
+	Cap    float64 // overrides the default cap
+}
+
+// Ranking of the pastweeks deviations of all processed items, written with -report
+type ReportConfiguration struct {
+	Sort   string // absolute (default) | relative
+	Limit  int    // entries per report or host group. all if 0
+	Group  bool   // one section per host group
+	Format string // text (default) | markdown | json | html
+}
+
+// Ranking of the items found by the item filters by their correlation with an anchor item on the same host.
+// The anchor uses the preprocessing of the first item filter matching it
+type CorrelationConfiguration struct {
+	Window     int64  // seconds of history. defaults to one day
+	Interval   int64  // seconds per bucket. defaults to 300
+	Lags       int    // largest shift in buckets tried in both directions. 0 compares the same buckets
+	Method     string // pearson (default) | spearman
+	Top        int    // number of ranked items. all if 0
+	MinSamples int    `yaml:"min_samples"` // buckets with values of both items needed for a result. defaults to 10
+	Format     string // text (default, aligned table) | json
+}
+
+// Dates and ranges excluded from the past weeks, e.g. public holidays
+type CalendarConfiguration struct {
+	Name   string
+	Groups []string // host groups the calendar applies to. all hosts if empty
+	File   string   // iCalendar (.ics) file with the events to exclude
+	Dates  []string // whole days, 2006-01-02
+	Ranges []CalendarRangeConfiguration
+}
+
+type CalendarRangeConfiguration struct {
+	From string // 2006-01-02 15:04 or 2006-01-02 (start of the day)
+	To   string // 2006-01-02 15:04 or 2006-01-02 (end of the day)
+}
+
+type FormulaConfiguration struct {
+	Key        string                               // item key receiving the result
+	Expression string                               // e.g. 100 * a / (a + b). functions abs, sqrt, log, min, max, clamp
+	Inputs     map[string]FormulaInputConfiguration // variables of the expression
+}
+
+// Formula variable: the latest value of the first item found by filter and search on the host,
+// or a value emitted by an algorithm for the host in this run
+type FormulaInputConfiguration struct {
+	Filter    map[string][]string
+	Search    map[string][]string
+	PastWeeks PastWeeksAlgorithmConfiguration // use the past weeks baseline of the item instead of the latest value
+	Output    string                          // derived key written in this run, e.g. system.cpu.load.3wd[all,avg1]
+}
+
+type StateConfiguration struct {
+	File string // json file. state is not kept if empty
 }
 
 type TemplateFilterConfiguration struct {
@@ -42,15 +127,209 @@ type HostFilterConfiguration struct {
 }
 
 type ItemConfiguration struct {
-	Filter    map[string][]string
-	Search    map[string][]string
-	PastWeeks PastWeeksAlgorithmConfiguration
-	Postfix   string
+	Filter        map[string][]string
+	Search        map[string][]string
+	Preprocessing PreprocessingConfiguration
+	Resample      ResampleConfiguration // buckets of holtwinters, stl, forecast and seasonality
+	PastWeeks     PastWeeksAlgorithmConfiguration
+	HoltWinters   HoltWintersAlgorithmConfiguration   `yaml:"holtwinters"`
+	STL           DecompositionAlgorithmConfiguration `yaml:"stl"`
+	Forecast      ForecastAlgorithmConfiguration
+	EWMA          EWMAAlgorithmConfiguration        `yaml:"ewma"`
+	ChangePoint   ChangePointAlgorithmConfiguration `yaml:"changepoint"`
+	Peers         PeerAlgorithmConfiguration
+	Aggregate     AggregateConfiguration
+	Quality       QualityAlgorithmConfiguration
+	Distribution  DistributionAlgorithmConfiguration
+	Seasonality   SeasonalityConfiguration
+	Schedule      ScheduleConfiguration
+	Log           LogAlgorithmConfiguration
+	TextChange    TextChangeAlgorithmConfiguration `yaml:"textchange"`
+	SLA           SLAAlgorithmConfiguration        `yaml:"sla"`
+	Parameters    map[int]string                   // key parameter (starting with 1) must match, * is a wildcard
+	KeyTemplate   string                           `yaml:"keytemplate"` // derived key, e.g. {key}.{postfix}[{params}]. see ItemKey.Derive
+	Postfix       string
+}
+
+// Conversion of a series into fixed interval buckets
+type ResampleConfiguration struct {
+	Aggregation string // avg (default) | min | max | last | count | sum
+	Fill        string // gap fill: previous (default) | linear | none
+	Timezone    string // align the buckets to interval boundaries in this timezone, e.g. Local or Europe/Zurich. not aligned if empty
+}
+
+// Conversion of raw counters, applied to the history before any algorithm of the item configuration
+type PreprocessingConfiguration struct {
+	Mode    string // rate (change per second) | delta (change per sample). values are used as stored if empty
+	Counter int    // counter size in bits (32 | 64) to detect wraps. a decrease is a counter reset if 0
 }
 
 type PastWeeksAlgorithmConfiguration struct {
-	Weeks  int
-	Window int64
+	Weeks   int
+	Window  int64
+	Outputs []OutputConfiguration // defaults to the absolute difference with the item postfix
+	Bands   BandConfiguration     // tolerance band for the lower and upper outputs
+	Exclude string                // weeks excluded by a calendar: skip (default) | replace (one more week back)
+}
+
+// Triple exponential smoothing (additive seasonality)
+type HoltWintersAlgorithmConfiguration struct {
+	Lookback   int64                 // seconds of history to fit on. enables the algorithm
+	Season     int64                 // seconds per season. defaults to one week
+	Interval   int64                 // seconds per sample bucket. defaults to one hour
+	Source     string                // history (default) | trends
+	Alpha      float64               // level smoothing. alpha, beta and gamma are estimated when all are zero
+	Beta       float64               // trend smoothing
+	Gamma      float64               // seasonal smoothing
+	Deviations float64               // width of the prediction interval in residual standard deviations. defaults to 2
+	Outputs    []OutputConfiguration // forecast, lower, upper, residual
+}
+
+// Seasonal-trend decomposition
+type DecompositionAlgorithmConfiguration struct {
+	Lookback int64                 // seconds of history to decompose. enables the algorithm
+	Period   int64                 // seconds per season. defaults to one week
+	Interval int64                 // seconds per sample bucket. defaults to one hour
+	Source   string                // history (default) | trends
+	Export   string                // directory receiving the full decomposition per item. optional
+	Format   string                // export format csv (default) | json
+	Outputs  []OutputConfiguration // trend, seasonal, remainder
+}
+
+// Time until a threshold is reached, extrapolated by regression
+type ForecastAlgorithmConfiguration struct {
+	Lookback  int64  // seconds of history to fit on. enables the algorithm
+	Method    string // linear (default) | theilsen
+	Interval  int64  // seconds per sample bucket. defaults to one hour
+	Source    string // history (default) | trends
+	Threshold ThresholdConfiguration
+	Outputs   []OutputConfiguration // timeleft (seconds), slope (per second), threshold
+}
+
+// Limit for the forecast. The first configured of item, macro and value is used
+type ThresholdConfiguration struct {
+	Value   float64 // fixed limit
+	Item    string  // key template of an item on the same host holding the limit, e.g. vfs.fs.size[{param1},total]
+	Macro   string  // user macro of the host, its templates or global holding the limit, e.g. {$LICENSE.LIMIT}
+	Percent float64 // percentage of the limit. defaults to 100
+}
+
+// Exponentially weighted moving average control chart
+type EWMAAlgorithmConfiguration struct {
+	Alpha    float64               // smoothing factor (0..1). enables the algorithm
+	Limit    float64               // control limit in standard deviations. defaults to 3
+	Lookback int64                 // seconds of history used without saved state. defaults to one day
+	Outputs  []OutputConfiguration // smoothed, lower, upper, score, outofcontrol
+}
+
+// Detection of a level shift in the recent history
+type ChangePointAlgorithmConfiguration struct {
+	Lookback  int64                 // seconds of history to inspect. enables the algorithm
+	Reference int64                 // seconds at the start of the lookback defining the normal level. defaults to half the lookback
+	Method    string                // cusum (default) | pagehinkley
+	K         float64               // allowed slack in standard deviations of the reference. defaults to 0.5
+	H         float64               // decision threshold in standard deviations of the reference. defaults to 5
+	Outputs   []OutputConfiguration // changetime (unix time), magnitude, shifted (1/0)
+}
+
+// Comparison of the same item key across all selected hosts
+type PeerAlgorithmConfiguration struct {
+	Window  int64                 // seconds back to look for the latest value of each host. enables the algorithm
+	Minimum int                   // minimum number of hosts with a value. defaults to 3
+	Outputs []OutputConfiguration // score (robust z-score), median, difference (value - median)
+}
+
+// Values of all found items combined per time bucket and sent to a virtual host
+type AggregateConfiguration struct {
+	Host     string                // host receiving the aggregated values. enables the aggregation
+	Key      string                // item key of the aggregate. the output postfix is inserted
+	Interval int64                 // seconds per bucket. defaults to 60
+	Lookback int64                 // seconds to aggregate. defaults to one interval
+	Outputs  []OutputConfiguration // sum, avg, min, max, count or a percentile like p95. defaults to avg with postfix .avg
+}
+
+// Data quality of the collection: stale values, missing samples and flatlines
+type QualityAlgorithmConfiguration struct {
+	Window   int64                 // seconds to count samples in. enables the algorithm
+	Flatline int                   // number of unchanged intervals reported as flatline. defaults to 10
+	Outputs  []OutputConfiguration // age (seconds), agefactor (age / delay), missing, missingratio, unchanged, flatline (1/0)
+}
+
+// Comparison of the value distribution of the last window with the same slot of the past weeks
+type DistributionAlgorithmConfiguration struct {
+	Window  int64                 // seconds of recent history to compare. enables the algorithm
+	Weeks   int                   // number of past weeks. defaults to 4
+	Outputs []OutputConfiguration // ks (Kolmogorov-Smirnov statistic), pvalue, p50shift, p95shift, p99shift
+}
+
+// Detection of a daily or weekly period by autocorrelation
+type SeasonalityConfiguration struct {
+	Lookback  int64                 // seconds of history to analyze. enables the detection. at least two weeks for weekly periods
+	Interval  int64                 // seconds per bucket. defaults to 3600
+	Source    string                // history | trends (default)
+	Threshold float64               // minimum autocorrelation of a period. defaults to 0.3
+	Apply     bool                  // enable the algorithm suited for the period (pastweeks, holtwinters or ewma) if not configured
+	Outputs   []OutputConfiguration // period (seconds, 0 for none), strength (autocorrelation)
+}
+
+// Counting of regular expression matches in log (type 2), text (type 4) and character (type 1) items
+type LogAlgorithmConfiguration struct {
+	Interval int64                     // seconds per bucket. the last complete bucket is counted. enables the algorithm
+	Weeks    int                       // number of past weeks for the <name>.baseline and <name>.change outputs
+	Patterns []LogPatternConfiguration // counted patterns
+	Outputs  []OutputConfiguration     // <name> (count), <name>.baseline (average of the past weeks), <name>.change (count - baseline)
+}
+
+type LogPatternConfiguration struct {
+	Name       string // output type of the count
+	Regex      string // regular expression (RE2 syntax). matches all values if empty
+	Severities []int  // log severities to count. all if empty
+	EventIDs   []int  `yaml:"eventids"` // logeventid values to count. all if empty
+}
+
+// Change of character (type 1) and text (type 4) values against the most common value
+type TextChangeAlgorithmConfiguration struct {
+	Weeks   int                   // number of past weeks to find the most common value in. enables the algorithm
+	Outputs []OutputConfiguration // differs (1/0), current, common and previous (text)
+}
+
+// Availability over a calendar period
+type SLAAlgorithmConfiguration struct {
+	Period      string                 // day | week | month (default). enables the algorithm
+	Offset      int                    // periods back. 0 is the current period up to now, 1 the previous complete period
+	Down        ConditionConfiguration // down condition. defaults to value = 0 (net.tcp.service, icmpping)
+	Maintenance []string               // schedule windows excluded in addition to the Zabbix maintenances of the host, e.g. sun 02:00-04:00
+	Timezone    string                 // timezone of the period boundaries and the maintenances, should match the Zabbix server. defaults to the local timezone
+	Outputs     []OutputConfiguration  // uptime (percent), downtime (minutes), outages, excluded (minutes)
+}
+
+// Comparison of a value with a constant
+type ConditionConfiguration struct {
+	Operator string  // = (default), !=, <, <=, >, >=
+	Value    float64 // compared constant
+}
+
+// Time windows the item is processed in
+type ScheduleConfiguration struct {
+	Windows  []string // e.g. mon-fri 08:00-18:00, sat,sun 10:00-14:00 or mon-fri (whole days). always processed if empty
+	Timezone string   // e.g. Europe/Zurich. defaults to the local timezone
+	Neutral  *float64 // emitted instead of the deviations and scores outside the windows. nothing is emitted if omitted
+	Baseline bool     // use only samples inside the windows for the baseline
+}
+
+// Tolerance band around the expected value
+type BandConfiguration struct {
+	Method string  // minmax (default) | stddev | percentile
+	K      float64 // stddev: number of standard deviations. defaults to 2
+	Lower  float64 // percentile: lower percentile. defaults to 5 if upper is missing as well
+	Upper  float64 // percentile: upper percentile. defaults to 95
+}
+
+// Derived value emitted by an algorithm
+type OutputConfiguration struct {
+	Type    string // algorithm specific value, e.g. absolute, percent, ratio or zscore
+	Postfix string // inserted into the item key
+	Key     string // template of the derived key. the item keytemplate if empty
 }
 
 func ReadConfigurationFromFile(filename string) (Configuration, error) {
@@ -16,6 +16,8 @@ func TestReadConfigurationFromFile(t *testing.T) {
 	configuration, err := ReadConfigurationFromFile(CONFIGURATION_EXAMPLE)
 	assert.Nil(t, err)
 	validateConfiguration(t, configuration)
+	validateAlgorithms(t, configuration)
+	validateSections(t, configuration)
 }
 
 func TestFilter(t *testing.T) {
@@ -45,7 +47,7 @@ func TestFilterToJson(t *testing.T) {
 	assert.Nil(t, err)
 	fmt.Fprintf(os.Stdout, "template 1 to json: %s\n", jsonString)
 
-	assert.Equal(t, 2, len(configuration.Items))
+	assert.Equal(t, 5, len(configuration.Items))
 	fmt.Fprintf(os.Stdout, "item 1: %v\n", configuration.Items[0])
 }
 
@@ -75,6 +77,8 @@ func TestExampleConfiguration(t *testing.T) {
 	fmt.Printf("parsed Configuration structure %v\n", configuration)
 
 	validateConfiguration(t, configuration)
+	validateAlgorithms(t, configuration)
+	validateSections(t, configuration)
 }
 
 func validateConfiguration(t *testing.T, configuration Configuration) {
@@ -88,3 +92,94 @@ func validateConfiguration(t *testing.T, configuration Configuration) {
 	assert.Equal(t, "127.0.0.1", configuration.Zabbix.Sender.Host)
 	assert.Equal(t, 10051, configuration.Zabbix.Sender.Port)
 }
+
+type expectation struct {
+	name     string
+	expected interface{}
+	actual   interface{}
+}
+
+func assertExpectations(t *testing.T, expectations []expectation) {
+	for _, e := range expectations {
+		assert.Equal(t, e.expected, e.actual, e.name)
+	}
+}
+
+// algorithm settings of the item filters
+func validateAlgorithms(t *testing.T, configuration Configuration) {
+	assert.Len(t, configuration.Items, 5)
+	first, second := configuration.Items[0], configuration.Items[1]
+	assertExpectations(t, []expectation{
+		{"pastweeks outputs", 7, len(first.PastWeeks.Outputs)},
+		{"pastweeks zscore", OutputConfiguration{Type: "zscore", Postfix: ".3wdz"}, first.PastWeeks.Outputs[3]},
+		{"pastweeks bands", BandConfiguration{Method: "stddev", K: 2}, first.PastWeeks.Bands},
+		{"pastweeks default outputs", 0, len(second.PastWeeks.Outputs)},
+		{"pastweeks exclude", "replace", second.PastWeeks.Exclude},
+		{"holtwinters lookback", int64(2419200), second.HoltWinters.Lookback},
+		{"holtwinters source", "trends", second.HoltWinters.Source},
+		{"holtwinters outputs", 2, len(second.HoltWinters.Outputs)},
+		{"holtwinters disabled", int64(0), first.HoltWinters.Lookback},
+		{"stl", []interface{}{int64(86400), "/tmp", "csv", "remainder"},
+			[]interface{}{first.STL.Period, first.STL.Export, first.STL.Format, first.STL.Outputs[1].Type}},
+		{"forecast method", "theilsen", second.Forecast.Method},
+		{"forecast threshold", ThresholdConfiguration{Item: "vfs.fs.size[{param1},total]", Macro: "{$DISK.LIMIT}", Value: 1000, Percent: 90},
+			second.Forecast.Threshold},
+		{"ewma", []interface{}{0.3, 3.0, "outofcontrol"}, []interface{}{first.EWMA.Alpha, first.EWMA.Limit, first.EWMA.Outputs[1].Type}},
+		{"changepoint", []interface{}{int64(43200), "cusum", 5.0},
+			[]interface{}{second.ChangePoint.Reference, second.ChangePoint.Method, second.ChangePoint.H}},
+		{"peers", []interface{}{int64(300), 3, ".peerscore"}, []interface{}{first.Peers.Window, first.Peers.Minimum, first.Peers.Outputs[0].Postfix}},
+		{"aggregate", []interface{}{"Linux servers", "grpcpu[load]", "p95"},
+			[]interface{}{first.Aggregate.Host, first.Aggregate.Key, first.Aggregate.Outputs[1].Type}},
+		{"preprocessing", PreprocessingConfiguration{Mode: "rate", Counter: 64}, first.Preprocessing},
+		{"no preprocessing", "", second.Preprocessing.Mode},
+		{"quality", []interface{}{int64(3600), 10, "agefactor"},
+			[]interface{}{second.Quality.Window, second.Quality.Flatline, second.Quality.Outputs[0].Type}},
+		{"key template", "{key}{postfix}[{params}]", second.KeyTemplate},
+		{"parameters", map[int]string{3: "80*"}, second.Parameters},
+		{"output key template", "quality[{key},{postfix}]", second.Quality.Outputs[2].Key},
+		{"no key template", "", first.KeyTemplate},
+		{"distribution", []interface{}{int64(14400), 4, "p99shift"},
+			[]interface{}{second.Distribution.Window, second.Distribution.Weeks, second.Distribution.Outputs[2].Type}},
+		{"no distribution", int64(0), first.Distribution.Window},
+		{"seasonality", []interface{}{int64(2419200), "trends", 0.3, false},
+			[]interface{}{second.Seasonality.Lookback, second.Seasonality.Source, second.Seasonality.Threshold, second.Seasonality.Apply}},
+		{"schedule", []interface{}{[]string{"mon-fri 08:00-18:00"}, "Europe/Zurich", 0.0, true},
+			[]interface{}{second.Schedule.Windows, second.Schedule.Timezone, *second.Schedule.Neutral, second.Schedule.Baseline}},
+		{"no neutral value", true, first.Schedule.Neutral == nil},
+		{"resample", ResampleConfiguration{Aggregation: "avg", Fill: "linear", Timezone: "Local"}, second.Resample},
+		{"no resample", ResampleConfiguration{}, first.Resample},
+	})
+
+	logs, text, sla := configuration.Items[2].Log, configuration.Items[3].TextChange, configuration.Items[4].SLA
+	assertExpectations(t, []expectation{
+		{"log", []interface{}{int64(300), 2, "errors", "errors.change"},
+			[]interface{}{logs.Interval, logs.Weeks, logs.Patterns[0].Name, logs.Outputs[1].Type}},
+		{"log pattern", []interface{}{[]int{1, 2}, []int{4624, 4625}}, []interface{}{logs.Patterns[1].Severities, logs.Patterns[1].EventIDs}},
+		{"textchange", []interface{}{4, "previous"}, []interface{}{text.Weeks, text.Outputs[1].Type}},
+		{"no textchange", 0, first.TextChange.Weeks},
+		{"sla", []interface{}{"month", 1, []string{"sun 02:00-04:00"}, "outages"},
+			[]interface{}{sla.Period, sla.Offset, sla.Maintenance, sla.Outputs[2].Type}},
+		{"sla down", ConditionConfiguration{Operator: "=", Value: 0}, sla.Down},
+	})
+}
+
+// top level sections
+func validateSections(t *testing.T, configuration Configuration) {
+	assert.Len(t, configuration.Formulas, 2)
+	assert.Len(t, configuration.Calendars, 2)
+	assert.Len(t, configuration.Health.Weights, 3)
+	assertExpectations(t, []expectation{
+		{"state", "/tmp/zabbixtools.state.json", configuration.State.File},
+		{"formula key", "http.error.ratio", configuration.Formulas[0].Key},
+		{"formula input", []string{"web.requests[error]"}, configuration.Formulas[0].Inputs["errors"].Filter["key_"]},
+		{"formula pastweeks input", 3, configuration.Formulas[1].Inputs["baseline"].PastWeeks.Weeks},
+		{"calendar", []interface{}{"conf/holidays.ics", "2019-06-30", []string{"Shops"}},
+			[]interface{}{configuration.Calendars[0].File, configuration.Calendars[0].Ranges[0].To, configuration.Calendars[1].Groups}},
+		{"correlation", CorrelationConfiguration{Window: 86400, Interval: 300, Lags: 6, Method: "spearman", Top: 10, MinSamples: 12, Format: "json"},
+			configuration.Correlation},
+		{"health", []interface{}{"health.score", "health.worst", 5.0, 0.0},
+			[]interface{}{configuration.Health.Key, configuration.Health.Contributor, configuration.Health.Cap, configuration.Health.Weights[2].Weight}},
+		{"health weight", HealthWeightConfiguration{Key: "net.tcp.service.perf*", Weight: 1, Cap: 3}, configuration.Health.Weights[1]},
+		{"report", ReportConfiguration{Sort: "relative", Limit: 20, Group: true, Format: "html"}, configuration.Report},
+	})
+}
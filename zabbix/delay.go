@@ -0,0 +1,453 @@
+package zabbix
+
+/**
+ * Item update interval according to https://www.zabbix.com/documentation/4.0/manual/config/items/item/custom_intervals
+ *
+ * <update interval>[;<flexible interval>|<scheduling interval>]...
+ * flexible:   50s/1-5,09:00-18:00
+ * scheduling: wd1-5h9-18m0-59/5
+ */
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Delay struct {
+	Interval   time.Duration // default update interval. 0 if the item only uses scheduling intervals
+	Flexible   []FlexibleInterval
+	Scheduling []SchedulingInterval
+}
+
+// Update interval used during a period
+type FlexibleInterval struct {
+	Interval time.Duration // 0 disables the collection during the period
+	Period   TimePeriod
+}
+
+// Days of the week (1 = Monday .. 7 = Sunday) and a time of day range
+type TimePeriod struct {
+	FromDay int
+	ToDay   int
+	From    time.Duration // since midnight
+	To      time.Duration // since midnight, exclusive
+}
+
+// Collection at the points in time matching all filters
+type SchedulingInterval struct {
+	filters map[string][]scheduleRange // md, wd, h, m, s
+}
+
+type scheduleRange struct {
+	from, to, step int
+}
+
+var macroPattern = regexp.MustCompile(`\{\$[A-Z0-9_.]+(:[^}]*)?\}`)
+
+type scheduleUnit struct {
+	name     string
+	min, max int
+}
+
+// order from the largest to the smallest unit
+var scheduleUnits = []scheduleUnit{{"md", 1, 31}, {"wd", 1, 7}, {"h", 0, 23}, {"m", 0, 59}, {"s", 0, 59}}
+
+// Host ID to user macro to value, global macros under the empty host ID
+var macroCache = make(map[string]map[string]string)
+
+/**
+ * Global, template and host user macros of a host. Host macros override the macros of the linked templates,
+ * which override global macros. Macros of nested templates are not resolved
+ */
+func (s *Session) HostMacros(hostID string) map[string]string {
+	if macros, found := macroCache[hostID]; found {
+		return macros
+	}
+	if _, found := macroCache[""]; !found {
+		query := s.NewUserMacroQuery(nil)
+		query.Global = true
+		global := make(map[string]string)
+		for _, macro := range query.Query() {
+			global[macro.Macro] = macro.Value
+		}
+		macroCache[""] = global
+	}
+
+	macros := make(map[string]string)
+	for name, value := range macroCache[""] {
+		macros[name] = value
+	}
+	hostQuery := s.NewHostQuery(nil, nil, nil)
+	hostQuery.HostIDs = []string{hostID}
+	hostQuery.SelectParentTemplates = "extend"
+	templateIDs := make([]string, 0)
+	for _, host := range hostQuery.Query() {
+		for _, template := range host.Templates {
+			templateIDs = append(templateIDs, template.TemplateId)
+		}
+	}
+	if len(templateIDs) > 0 {
+		query := s.NewUserMacroQuery(templateIDs)
+		for _, macro := range query.Query() {
+			macros[macro.Macro] = macro.Value
+		}
+	}
+
+	query := s.NewUserMacroQuery([]string{hostID})
+	for _, macro := range query.Query() {
+		macros[macro.Macro] = macro.Value
+	}
+	macroCache[hostID] = macros
+	return macros
+}
+
+/**
+ * Parsed update interval of the item with user macros resolved
+ */
+func (s *Session) ItemDelay(item ItemResponseElement) (Delay, error) {
+	delay, err := ParseDelay(item.Delay, nil)
+	if err != nil && strings.Contains(item.Delay, "{$") {
+		delay, err = ParseDelay(item.Delay, s.HostMacros(item.HostID))
+	}
+	return delay, err
+}
+
+/**
+ * Parse the delay of an item. User macros are replaced with the values from macros, e.g. {$DELAY} -> 5m
+ */
+func ParseDelay(text string, macros map[string]string) (Delay, error) {
+	var unresolved error
+	text = macroPattern.ReplaceAllStringFunc(text, func(macro string) string {
+		if value, found := macros[macro]; found {
+			return value
+		}
+		// macro with context falls back to the macro without context
+		if i := strings.Index(macro, ":"); i > 0 {
+			if value, found := macros[macro[:i]+"}"]; found {
+				return value
+			}
+		}
+		unresolved = fmt.Errorf("unresolved user macro %s", macro)
+		return macro
+	})
+	if unresolved != nil {
+		return Delay{}, unresolved
+	}
+
+	parts := strings.Split(strings.TrimSpace(text), ";")
+	delay := Delay{}
+	var err error
+	delay.Interval, err = parseDuration(parts[0])
+	if err != nil {
+		return Delay{}, err
+	}
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// only flexible intervals contain a time of day
+		if strings.Contains(part, ":") {
+			flexible, err := parseFlexible(part)
+			if err != nil {
+				return Delay{}, err
+			}
+			delay.Flexible = append(delay.Flexible, flexible)
+		} else {
+			scheduling, err := parseScheduling(part)
+			if err != nil {
+				return Delay{}, err
+			}
+			delay.Scheduling = append(delay.Scheduling, scheduling)
+		}
+	}
+	if delay.Interval == 0 && len(delay.Scheduling) == 0 && len(delay.Flexible) == 0 {
+		return Delay{}, fmt.Errorf("delay %q never collects", text)
+	}
+	return delay, nil
+}
+
+/**
+ * Time suffixes s, m, h, d and w. Plain numbers are seconds
+ */
+func parseDuration(text string) (time.Duration, error) {
+	text = strings.TrimSpace(text)
+	units := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': time.Hour * 24, 'w': time.Hour * 24 * 7}
+	unit := time.Second
+	if len(text) > 0 {
+		if u, found := units[text[len(text)-1]]; found {
+			unit = u
+			text = text[:len(text)-1]
+		}
+	}
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid interval %q", text)
+	}
+	return time.Duration(value) * unit, nil
+}
+
+func parseFlexible(text string) (FlexibleInterval, error) {
+	parts := strings.SplitN(text, "/", 2)
+	if len(parts) != 2 {
+		return FlexibleInterval{}, fmt.Errorf("invalid flexible interval %q", text)
+	}
+	interval, err := parseDuration(parts[0])
+	if err != nil {
+		return FlexibleInterval{}, err
+	}
+	period, err := ParseTimePeriod(parts[1])
+	return FlexibleInterval{Interval: interval, Period: period}, err
+}
+
+/**
+ * Parse a time period like 1-5,09:00-18:00 or 6,0:00-24:00
+ */
+func ParseTimePeriod(text string) (TimePeriod, error) {
+	invalid := fmt.Errorf("invalid time period %q", text)
+	parts := strings.SplitN(strings.TrimSpace(text), ",", 2)
+	if len(parts) != 2 {
+		return TimePeriod{}, invalid
+	}
+	days := strings.SplitN(parts[0], "-", 2)
+	period := TimePeriod{}
+	var err error
+	if period.FromDay, err = strconv.Atoi(days[0]); err != nil {
+		return TimePeriod{}, invalid
+	}
+	period.ToDay = period.FromDay
+	if len(days) == 2 {
+		if period.ToDay, err = strconv.Atoi(days[1]); err != nil {
+			return TimePeriod{}, invalid
+		}
+	}
+	times := strings.SplitN(parts[1], "-", 2)
+	if len(times) != 2 {
+		return TimePeriod{}, invalid
+	}
+	if period.From, err = parseTimeOfDay(times[0]); err != nil {
+		return TimePeriod{}, err
+	}
+	if period.To, err = parseTimeOfDay(times[1]); err != nil {
+		return TimePeriod{}, err
+	}
+	if period.FromDay < 1 || period.ToDay > 7 || period.FromDay > period.ToDay || period.From >= period.To {
+		return TimePeriod{}, invalid
+	}
+	return period, nil
+}
+
+func parseTimeOfDay(text string) (time.Duration, error) {
+	parts := strings.SplitN(text, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 || hours > 24 {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 0 || minutes > 59 || (hours == 24 && minutes > 0) {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+/**
+ * True if the time is inside the period
+ */
+func (p TimePeriod) Contains(t time.Time) bool {
+	day := int(t.Weekday())
+	if day == 0 {
+		day = 7
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	since := t.Sub(midnight)
+	return day >= p.FromDay && day <= p.ToDay && since >= p.From && since < p.To
+}
+
+var schedulePattern = regexp.MustCompile(`^(md|wd|h|m|s)([0-9,/-]*)`)
+
+func parseScheduling(text string) (SchedulingInterval, error) {
+	scheduling := SchedulingInterval{filters: make(map[string][]scheduleRange)}
+	rest := text
+	for rest != "" {
+		match := schedulePattern.FindStringSubmatch(rest)
+		if match == nil {
+			return SchedulingInterval{}, fmt.Errorf("invalid scheduling interval %q", text)
+		}
+		rest = rest[len(match[0]):]
+		unit := unitBounds(match[1])
+		for _, filter := range strings.Split(match[2], ",") {
+			r := scheduleRange{from: unit.min, to: unit.max, step: 1}
+			bounds := filter
+			if i := strings.Index(filter, "/"); i >= 0 {
+				step, err := strconv.Atoi(filter[i+1:])
+				if err != nil || step < 1 {
+					return SchedulingInterval{}, fmt.Errorf("invalid step in %q", text)
+				}
+				r.step = step
+				bounds = filter[:i]
+			}
+			if bounds != "" {
+				limits := strings.SplitN(bounds, "-", 2)
+				from, err := strconv.Atoi(limits[0])
+				if err != nil {
+					return SchedulingInterval{}, fmt.Errorf("invalid scheduling interval %q", text)
+				}
+				r.from, r.to = from, from
+				if len(limits) == 2 {
+					if r.to, err = strconv.Atoi(limits[1]); err != nil {
+						return SchedulingInterval{}, fmt.Errorf("invalid scheduling interval %q", text)
+					}
+				} else if r.step > 1 {
+					r.to = unit.max
+				}
+			}
+			if r.from < unit.min || r.to > unit.max || r.from > r.to {
+				return SchedulingInterval{}, fmt.Errorf("value out of range in %q", text)
+			}
+			scheduling.filters[match[1]] = append(scheduling.filters[match[1]], r)
+		}
+	}
+
+	// smaller units than the smallest given default to their minimum, e.g. h9 is 09:00:00
+	smallest := -1
+	for i, unit := range scheduleUnits {
+		if _, found := scheduling.filters[unit.name]; found {
+			smallest = i
+		}
+	}
+	for _, unit := range scheduleUnits[smallest+1:] {
+		if unit.name != "wd" && unit.name != "md" {
+			scheduling.filters[unit.name] = []scheduleRange{{from: unit.min, to: unit.min, step: 1}}
+		}
+	}
+	return scheduling, nil
+}
+
+func unitBounds(name string) scheduleUnit {
+	for _, unit := range scheduleUnits {
+		if unit.name == name {
+			return unit
+		}
+	}
+	return scheduleUnits[0]
+}
+
+/**
+ * True if the scheduling interval collects at the (second) t
+ */
+func (s SchedulingInterval) Matches(t time.Time) bool {
+	return s.matchesDay(t) && s.matchesUnit("h", t.Hour()) && s.matchesUnit("m", t.Minute()) && s.matchesUnit("s", t.Second())
+}
+
+func (s SchedulingInterval) matchesDay(t time.Time) bool {
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return s.matchesUnit("md", t.Day()) && s.matchesUnit("wd", weekday)
+}
+
+/**
+ * True if the value is in a range of the unit. Units without filter match any value
+ */
+func (s SchedulingInterval) matchesUnit(unit string, value int) bool {
+	ranges, found := s.filters[unit]
+	if !found {
+		return true
+	}
+	for _, r := range ranges {
+		if value >= r.from && value <= r.to && (value-r.from)%r.step == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Values of the unit in the ranges of its filter. All values of the unit without filter
+ */
+func (s SchedulingInterval) unitValues(name string) []int {
+	unit := unitBounds(name)
+	ranges, found := s.filters[name]
+	if !found {
+		ranges = []scheduleRange{{from: unit.min, to: unit.max, step: 1}}
+	}
+	values := make([]int, 0)
+	for _, r := range ranges {
+		for value := r.from; value <= r.to; value += r.step {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+/**
+ * Points in time in [from, to) the scheduling interval collects at, day by day
+ */
+func (s SchedulingInterval) Slots(from time.Time, to time.Time) []time.Time {
+	hours, minutes, seconds := s.unitValues("h"), s.unitValues("m"), s.unitValues("s")
+	slots := make([]time.Time, 0)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !s.matchesDay(day) {
+			continue
+		}
+		for _, hour := range hours {
+			for _, minute := range minutes {
+				for _, second := range seconds {
+					t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, day.Location())
+					if !t.Before(from) && t.Before(to) {
+						slots = append(slots, t)
+					}
+				}
+			}
+		}
+	}
+	return slots
+}
+
+/**
+ * Update interval at time t. Overlapping flexible intervals use the shortest interval
+ */
+func (d Delay) IntervalAt(t time.Time) time.Duration {
+	interval := d.Interval
+	found := false
+	for _, flexible := range d.Flexible {
+		if flexible.Period.Contains(t) && (!found || flexible.Interval < interval) {
+			interval = flexible.Interval
+			found = true
+		}
+	}
+	return interval
+}
+
+/**
+ * Number of values the item should collect in [from, to)
+ */
+func (d Delay) ExpectedSamples(from time.Time, to time.Time) int {
+	count := 0
+	for t := from; t.Before(to); {
+		interval := d.IntervalAt(t)
+		if interval > 0 {
+			count++
+			t = t.Add(interval)
+		} else {
+			// collection disabled, check again in a minute
+			t = t.Add(time.Minute)
+		}
+	}
+	// a point in time matching several scheduling intervals is collected once
+	collected := make(map[int64]bool)
+	for _, scheduling := range d.Scheduling {
+		for _, t := range scheduling.Slots(from.Truncate(time.Second), to) {
+			collected[t.Unix()] = true
+		}
+	}
+	return count + len(collected)
+}
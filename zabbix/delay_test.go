@@ -0,0 +1,88 @@
+package zabbix
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// Monday
+var monday = time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC)
+
+func TestParseSimpleDelay(t *testing.T) {
+	for text, expected := range map[string]time.Duration{"60": time.Minute, "30s": 30 * time.Second, "5m": 5 * time.Minute, "2h": 2 * time.Hour, "1d": 24 * time.Hour, "1w": 7 * 24 * time.Hour} {
+		delay, err := ParseDelay(text, nil)
+		assert.Nil(t, err, text)
+		assert.Equal(t, expected, delay.Interval, text)
+	}
+	for _, text := range []string{"", "x", "-5", "0", "{$DELAY}", "1m;wd9", "1m;50s/1-5"} {
+		_, err := ParseDelay(text, nil)
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestParseDelayMacros(t *testing.T) {
+	macros := map[string]string{"{$DELAY}": "5m", "{$OFFICE}": "1-5,08:00-18:00"}
+	delay, err := ParseDelay("{$DELAY}", macros)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Minute, delay.Interval)
+
+	delay, err = ParseDelay(`{$DELAY:"eth0"};30s/{$OFFICE}`, macros)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Minute, delay.Interval)
+	assert.Equal(t, 30*time.Second, delay.Flexible[0].Interval)
+}
+
+func TestFlexibleInterval(t *testing.T) {
+	delay, err := ParseDelay("10m;1m/1-5,09:00-18:00;0/6-7,00:00-24:00", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(delay.Flexible))
+	assert.Equal(t, TimePeriod{FromDay: 1, ToDay: 5, From: 9 * time.Hour, To: 18 * time.Hour}, delay.Flexible[0].Period)
+
+	assert.Equal(t, 10*time.Minute, delay.IntervalAt(monday.Add(8*time.Hour)))
+	assert.Equal(t, time.Minute, delay.IntervalAt(monday.Add(9*time.Hour)))
+	assert.Equal(t, 10*time.Minute, delay.IntervalAt(monday.Add(18*time.Hour)))
+	assert.Equal(t, time.Duration(0), delay.IntervalAt(monday.Add(5*24*time.Hour+12*time.Hour)))
+
+	assert.Equal(t, 6, delay.ExpectedSamples(monday, monday.Add(time.Hour)))
+	assert.Equal(t, 60, delay.ExpectedSamples(monday.Add(9*time.Hour), monday.Add(10*time.Hour)))
+	assert.Equal(t, 0, delay.ExpectedSamples(monday.Add(5*24*time.Hour), monday.Add(6*24*time.Hour)))
+}
+
+func TestSchedulingInterval(t *testing.T) {
+	delay, err := ParseDelay("0;wd1-5h9-18", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), delay.Interval)
+	scheduling := delay.Scheduling[0]
+	assert.True(t, scheduling.Matches(monday.Add(9*time.Hour)))
+	assert.False(t, scheduling.Matches(monday.Add(9*time.Hour+time.Minute)))
+	assert.False(t, scheduling.Matches(monday.Add(8*time.Hour)))
+	assert.False(t, scheduling.Matches(monday.Add(5*24*time.Hour+9*time.Hour)))
+	assert.Equal(t, 10, delay.ExpectedSamples(monday, monday.Add(24*time.Hour)))
+
+	delay, err = ParseDelay("0;m0-59/15", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, delay.ExpectedSamples(monday, monday.Add(time.Hour)))
+
+	delay, err = ParseDelay("0;h/6;md1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, delay.ExpectedSamples(monday, monday.Add(24*time.Hour)))
+	assert.True(t, delay.Scheduling[1].Matches(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestSchedulingSlots(t *testing.T) {
+	delay, err := ParseDelay("0;wd1-5h9-10m0-59/30", nil)
+	assert.Nil(t, err)
+	slots := delay.Scheduling[0].Slots(monday.Add(9*time.Hour+time.Second), monday.Add(7*24*time.Hour))
+	// monday 09:00 is before from, saturday and sunday do not match
+	assert.Equal(t, 5*4-1, len(slots))
+	assert.Equal(t, monday.Add(9*time.Hour+30*time.Minute), slots[0])
+	for _, slot := range slots {
+		assert.True(t, delay.Scheduling[0].Matches(slot))
+	}
+
+	// overlapping intervals count once
+	delay, err = ParseDelay("0;m0-59/15;m0-59/30", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, delay.ExpectedSamples(monday, monday.Add(time.Hour)))
+}
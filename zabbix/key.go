@@ -0,0 +1,224 @@
+package zabbix
+
+/**
+ * Item key according to https://www.zabbix.com/documentation/4.0/manual/config/items/item/key
+ *
+ * name[param1,"quoted, param",[array,element],...]
+ */
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type ItemKey struct {
+	Name       string
+	Parameters []KeyParameter
+	Bracketed  bool // key has a parameter list, possibly empty: name[]
+}
+
+type KeyParameter struct {
+	Value  string
+	Quoted bool
+	Array  []KeyParameter // elements if the parameter is an array
+}
+
+/**
+ * Parse an item key into name and parameters
+ */
+func ParseKey(key string) (ItemKey, error) {
+	i := 0
+	for i < len(key) && isKeyCharacter(key[i]) {
+		i++
+	}
+	if i == 0 {
+		return ItemKey{}, fmt.Errorf("invalid key name in %q", key)
+	}
+	parsed := ItemKey{Name: key[:i]}
+	if i == len(key) {
+		return parsed, nil
+	}
+	if key[i] != '[' {
+		return ItemKey{}, fmt.Errorf("unexpected %q at position %d in key %q", key[i], i, key)
+	}
+	parsed.Bracketed = true
+	parameters, end, err := parseParameters(key, i+1, 0)
+	if err != nil {
+		return ItemKey{}, err
+	}
+	if end != len(key) {
+		return ItemKey{}, fmt.Errorf("unexpected text after parameters in key %q", key)
+	}
+	parsed.Parameters = parameters
+	return parsed, nil
+}
+
+func isKeyCharacter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.'
+}
+
+/**
+ * Parse the parameters starting at position up to and including the closing bracket.
+ * Returns the position after the closing bracket.
+ */
+func parseParameters(key string, position int, depth int) ([]KeyParameter, int, error) {
+	parameters := make([]KeyParameter, 0)
+	for {
+		for position < len(key) && key[position] == ' ' {
+			position++
+		}
+		if position >= len(key) {
+			return nil, position, fmt.Errorf("missing ']' in key %q", key)
+		}
+
+		parameter := KeyParameter{}
+		switch key[position] {
+		case '"':
+			parameter.Quoted = true
+			position++
+			var value strings.Builder
+			for position < len(key) && key[position] != '"' {
+				if key[position] == '\\' && position+1 < len(key) && key[position+1] == '"' {
+					position++
+				}
+				value.WriteByte(key[position])
+				position++
+			}
+			if position >= len(key) {
+				return nil, position, fmt.Errorf("unterminated quoted parameter in key %q", key)
+			}
+			parameter.Value = value.String()
+			position++
+			for position < len(key) && key[position] == ' ' {
+				position++
+			}
+		case '[':
+			if depth > 0 {
+				return nil, position, fmt.Errorf("nested arrays are not supported in key %q", key)
+			}
+			elements, end, err := parseParameters(key, position+1, depth+1)
+			if err != nil {
+				return nil, end, err
+			}
+			parameter.Array = elements
+			position = end
+			for position < len(key) && key[position] == ' ' {
+				position++
+			}
+		default:
+			start := position
+			for position < len(key) && key[position] != ',' && key[position] != ']' {
+				position++
+			}
+			parameter.Value = strings.TrimRight(key[start:position], " ")
+		}
+
+		if position >= len(key) {
+			return nil, position, fmt.Errorf("missing ']' in key %q", key)
+		}
+		parameters = append(parameters, parameter)
+		switch key[position] {
+		case ',':
+			position++
+		case ']':
+			return parameters, position + 1, nil
+		default:
+			return nil, position, fmt.Errorf("unexpected %q at position %d in key %q", key[position], position, key)
+		}
+	}
+}
+
+/**
+ * Parameter at index (starting with 1 like $1 in item names). Arrays are returned in key syntax
+ */
+func (k ItemKey) Parameter(index int) (string, bool) {
+	if index < 1 || index > len(k.Parameters) {
+		return "", false
+	}
+	parameter := k.Parameters[index-1]
+	if parameter.Array != nil {
+		return parameter.String(), true
+	}
+	return parameter.Value, true
+}
+
+/**
+ * Parameter list without brackets
+ */
+func (k ItemKey) ParameterString() string {
+	parameters := make([]string, len(k.Parameters))
+	for i, parameter := range k.Parameters {
+		parameters[i] = parameter.String()
+	}
+	return strings.Join(parameters, ",")
+}
+
+func (k ItemKey) String() string {
+	if !k.Bracketed {
+		return k.Name
+	}
+	return k.Name + "[" + k.ParameterString() + "]"
+}
+
+func (p KeyParameter) String() string {
+	if p.Array != nil {
+		return "[" + ItemKey{Parameters: p.Array}.ParameterString() + "]"
+	}
+	if p.Quoted || strings.ContainsAny(p.Value, ",]\"") || strings.HasPrefix(p.Value, "[") || strings.HasPrefix(p.Value, " ") {
+		return "\"" + strings.Replace(p.Value, "\"", "\\\"", -1) + "\""
+	}
+	return p.Value
+}
+
+/**
+ * Build a derived key from a template. Placeholders:
+ * {key} key name, {postfix}, {params} parameter list, {param1}... single parameters, quoted if needed.
+ * An empty template appends the postfix to the key name. Empty brackets are dropped for keys without parameters.
+ * Placeholders of missing parameters are an error, the key is returned with them unchanged.
+ */
+func (k ItemKey) Derive(template string, postfix string) (string, error) {
+	if template == "" {
+		template = "{key}{postfix}[{params}]"
+	}
+	replacements := []string{"{key}", k.Name, "{postfix}", postfix, "{params}", k.ParameterString()}
+	// replace higher indices first, {param10} contains {param1}
+	for i := len(k.Parameters); i >= 1; i-- {
+		replacements = append(replacements, fmt.Sprintf("{param%d}", i), k.Parameters[i-1].String())
+	}
+	derived := strings.NewReplacer(replacements...).Replace(template)
+	if !k.Bracketed {
+		derived = strings.TrimSuffix(derived, "[]")
+	}
+	if unresolved := parameterPlaceholder.FindString(derived); unresolved != "" {
+		return derived, fmt.Errorf("key %s has no parameter for %s", k, unresolved)
+	}
+	return derived, nil
+}
+
+var parameterPlaceholder = regexp.MustCompile(`\{param[0-9]+\}`)
+
+/**
+ * True if the parameters at the given indices (starting with 1) match the patterns. * matches any text
+ */
+func (k ItemKey) MatchesParameters(patterns map[int]string) bool {
+	for index, pattern := range patterns {
+		value, found := k.Parameter(index)
+		if !found {
+			return false
+		}
+		if !MatchWildcard(pattern, value) {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ * True if text matches the pattern completely. * matches any text
+ */
+func MatchWildcard(pattern string, text string) bool {
+	expression := "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$"
+	matched, _ := regexp.MatchString(expression, text)
+	return matched
+}
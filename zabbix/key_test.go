@@ -0,0 +1,98 @@
+package zabbix
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	key, err := ParseKey(`net.tcp.service.perf["http",,"8080"]`)
+	assert.Nil(t, err)
+	assert.Equal(t, "net.tcp.service.perf", key.Name)
+	assert.Equal(t, 3, len(key.Parameters))
+	assert.Equal(t, KeyParameter{Value: "http", Quoted: true}, key.Parameters[0])
+	assert.Equal(t, KeyParameter{}, key.Parameters[1])
+	assert.Equal(t, `net.tcp.service.perf["http",,"8080"]`, key.String())
+
+	key, err = ParseKey("system.cpu.load")
+	assert.Nil(t, err)
+	assert.False(t, key.Bracketed)
+	assert.Equal(t, "system.cpu.load", key.String())
+}
+
+func TestParseKeyQuotedBrackets(t *testing.T) {
+	key, err := ParseKey(`log[/var/log/app.log,"error [0-9]+, \"x\"",, skip]`)
+	assert.Nil(t, err)
+	value, found := key.Parameter(2)
+	assert.True(t, found)
+	assert.Equal(t, `error [0-9]+, "x"`, value)
+	value, _ = key.Parameter(4)
+	assert.Equal(t, "skip", value)
+	assert.Equal(t, `log[/var/log/app.log,"error [0-9]+, \"x\"",,skip]`, key.String())
+}
+
+func TestParseKeyArray(t *testing.T) {
+	key, err := ParseKey(`custom.check[a,["b,c", d],e]`)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(key.Parameters))
+	assert.Equal(t, 2, len(key.Parameters[1].Array))
+	value, _ := key.Parameter(2)
+	assert.Equal(t, `["b,c",d]`, value)
+	assert.Equal(t, `custom.check[a,["b,c",d],e]`, key.String())
+}
+
+func TestParseKeyErrors(t *testing.T) {
+	for _, text := range []string{"", "[a]", "key[a", `key["a]`, "key[a]b", "key[[[a]]]", "key name"} {
+		_, err := ParseKey(text)
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	key, _ := ParseKey(`system.cpu.util[,idle]`)
+	derived, err := key.Derive("", ".3wd")
+	assert.Nil(t, err)
+	assert.Equal(t, "system.cpu.util.3wd[,idle]", derived)
+	derived, err = key.Derive("baseline[{key},{param2}]", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "baseline[system.cpu.util,idle]", derived)
+
+	key, _ = ParseKey("system.cpu.switches")
+	derived, _ = key.Derive("", ".3wd")
+	assert.Equal(t, "system.cpu.switches.3wd", derived)
+	derived, _ = key.Derive("{key}{postfix}[{params}]", ".3wd")
+	assert.Equal(t, "system.cpu.switches.3wd", derived)
+}
+
+func TestDeriveKeyQuotesParameters(t *testing.T) {
+	key, err := ParseKey(`web.page.get["example.com","/a,b]",80]`)
+	assert.Nil(t, err)
+	derived, err := key.Derive("{key}{postfix}[{param2},{param3}]", ".3wd")
+	assert.Nil(t, err)
+	assert.Equal(t, `web.page.get.3wd["/a,b]",80]`, derived)
+	parsed, err := ParseKey(derived)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(parsed.Parameters))
+
+	derived, err = key.Derive("{key}{postfix}[{param4}]", ".3wd")
+	assert.NotNil(t, err)
+	assert.Equal(t, "web.page.get.3wd[{param4}]", derived)
+}
+
+func TestMatchesParameters(t *testing.T) {
+	key, err := ParseKey(`net.tcp.service.perf["http",,"8080"]`)
+	assert.Nil(t, err)
+	assert.True(t, key.MatchesParameters(nil))
+	assert.True(t, key.MatchesParameters(map[int]string{1: "http", 3: "80*"}))
+	assert.True(t, key.MatchesParameters(map[int]string{2: ""}))
+	assert.False(t, key.MatchesParameters(map[int]string{3: "80"}))
+	assert.False(t, key.MatchesParameters(map[int]string{4: "*"}))
+	assert.False(t, key.MatchesParameters(map[int]string{1: "h.tp"}))
+}
+
+func TestMatchWildcard(t *testing.T) {
+	assert.True(t, MatchWildcard("system.cpu*", "system.cpu.load[all,avg1]"))
+	assert.True(t, MatchWildcard("*[*]", "vfs.fs.size[/,free]"))
+	assert.False(t, MatchWildcard("system.cpu", "system.cpu.load"))
+	assert.False(t, MatchWildcard("a.c", "abc"))
+}
@@ -0,0 +1,143 @@
+package zabbix
+
+/**
+ * Maintenance periods according to https://www.zabbix.com/documentation/4.0/manual/maintenance
+ */
+
+import (
+	"math"
+	"time"
+)
+
+type MaintenanceElement struct {
+	MaintenanceID string `json:"maintenanceid"`
+	Name          string `json:"name"`
+	ActiveSince   int64  `json:"active_since,string"`
+	ActiveTill    int64  `json:"active_till,string"`
+	Hosts         []MaintenanceHost
+	Groups        []HostGroupElement
+	Periods       []MaintenancePeriod `json:"timeperiods"`
+}
+
+type MaintenanceHost struct {
+	HostID string `json:"hostid"`
+}
+
+type MaintenancePeriod struct {
+	Type      int   `json:"timeperiod_type,string"` // 0 one time only, 2 daily, 3 weekly, 4 monthly
+	Every     int   `json:"every,string"`           // days or weeks between periods. monthly: week of the month, 5 = last
+	Month     int   `json:"month,string"`           // bitmask, 1 = January .. 2048 = December
+	DayOfWeek int   `json:"dayofweek,string"`       // bitmask, 1 = Monday .. 64 = Sunday
+	Day       int   `json:"day,string"`             // day of the month. 0 uses every and dayofweek
+	StartTime int64 `json:"start_time,string"`      // seconds since midnight
+	Period    int64 `json:"period,string"`          // duration in seconds
+	StartDate int64 `json:"start_date,string"`      // one time only
+}
+
+const (
+	maintenanceOnce    = 0
+	maintenanceDaily   = 2
+	maintenanceWeekly  = 3
+	maintenanceMonthly = 4
+)
+
+/**
+ * True if the maintenance is assigned to the host or to one of its host groups
+ */
+func (m MaintenanceElement) AppliesTo(hostID string, groups []string) bool {
+	for _, host := range m.Hosts {
+		if host.HostID == hostID {
+			return true
+		}
+	}
+	for _, group := range m.Groups {
+		for _, name := range groups {
+			if group.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/**
+ * True if one of the periods of the maintenance covers t. Recurring periods are evaluated in the location,
+ * which should be the timezone of the Zabbix server
+ */
+func (m MaintenanceElement) Contains(t time.Time, location *time.Location) bool {
+	if t.Unix() < m.ActiveSince || t.Unix() >= m.ActiveTill {
+		return false
+	}
+	t = t.In(location)
+	since := midnight(time.Unix(m.ActiveSince, 0).In(location))
+	for _, period := range m.Periods {
+		if period.contains(t, since) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p MaintenancePeriod) contains(t time.Time, since time.Time) bool {
+	if p.Type == maintenanceOnce {
+		return t.Unix() >= p.StartDate && t.Unix() < p.StartDate+p.Period
+	}
+	// a recurring period may have started on one of the previous days
+	today := midnight(t)
+	for back := 0; back <= int(p.Period/86400)+1; back++ {
+		day := today.AddDate(0, 0, -back)
+		start := day.Add(time.Duration(p.StartTime) * time.Second)
+		if t.Before(start) || !t.Before(start.Add(time.Duration(p.Period)*time.Second)) {
+			continue
+		}
+		if p.startsOn(day, since) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * True if the recurring period starts on the day. Daily and weekly periods count from the day (week) the
+ * maintenance became active
+ */
+func (p MaintenancePeriod) startsOn(day time.Time, since time.Time) bool {
+	every := p.Every
+	if every < 1 {
+		every = 1
+	}
+	weekday := (int(day.Weekday()) + 6) % 7 // monday = 0
+	switch p.Type {
+	case maintenanceDaily:
+		days := daysBetween(since, day)
+		return days >= 0 && days%every == 0
+	case maintenanceWeekly:
+		monday := since.AddDate(0, 0, -((int(since.Weekday()) + 6) % 7))
+		days := daysBetween(monday, day)
+		return days >= 0 && p.DayOfWeek&(1<<uint(weekday)) != 0 && (days/7)%every == 0
+	case maintenanceMonthly:
+		if p.Month&(1<<uint(day.Month()-1)) == 0 {
+			return false
+		}
+		if p.Day > 0 {
+			return day.Day() == p.Day
+		}
+		if p.DayOfWeek&(1<<uint(weekday)) == 0 {
+			return false
+		}
+		if every == 5 {
+			return day.AddDate(0, 0, 7).Month() != day.Month()
+		}
+		return (day.Day()-1)/7+1 == every
+	}
+	return false
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// calendar days from a to b, both at midnight. Days with a daylight saving change are not 24h long
+func daysBetween(a time.Time, b time.Time) int {
+	return int(math.Round(b.Sub(a).Hours() / 24))
+}
@@ -0,0 +1,66 @@
+package zabbix
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceElement(t *testing.T) {
+	var maintenance MaintenanceElement
+	err := json.Unmarshal([]byte(`{"maintenanceid": "3", "name": "patching", "active_since": "1546300800", "active_till": "1577836800",
+		"hosts": [{"hostid": "10084"}], "groups": [{"groupid": "2", "name": "Linux servers"}],
+		"timeperiods": [{"timeperiod_type": "0", "every": "1", "month": "0", "dayofweek": "0", "day": "1",
+			"start_time": "0", "period": "3600", "start_date": "1546851600"}]}`), &maintenance)
+	assert.Nil(t, err)
+	assert.Equal(t, MaintenancePeriod{Type: 0, Every: 1, Day: 1, Period: 3600, StartDate: 1546851600}, maintenance.Periods[0])
+
+	assert.True(t, maintenance.AppliesTo("10084", nil))
+	assert.True(t, maintenance.AppliesTo("10085", []string{"Web", "Linux servers"}))
+	assert.False(t, maintenance.AppliesTo("10085", []string{"Web"}))
+
+	// one time 2019-01-07 09:00-10:00 UTC
+	assert.True(t, maintenance.Contains(time.Date(2019, 1, 7, 9, 30, 0, 0, time.UTC), time.UTC))
+	assert.False(t, maintenance.Contains(time.Date(2019, 1, 7, 10, 0, 0, 0, time.UTC), time.UTC))
+}
+
+func TestMaintenanceRecurringPeriods(t *testing.T) {
+	// active during 2019, Tuesday 2019-01-01 is the first day
+	maintenance := func(period MaintenancePeriod) MaintenanceElement {
+		return MaintenanceElement{ActiveSince: 1546300800, ActiveTill: 1577836800, Periods: []MaintenancePeriod{period}}
+	}
+	at := func(month time.Month, day int, hour int) time.Time {
+		return time.Date(2019, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	// every second day 22:00-02:00
+	daily := maintenance(MaintenancePeriod{Type: 2, Every: 2, StartTime: 22 * 3600, Period: 4 * 3600})
+	assert.True(t, daily.Contains(at(1, 3, 23), time.UTC))
+	assert.True(t, daily.Contains(at(1, 4, 1), time.UTC))
+	assert.False(t, daily.Contains(at(1, 4, 23), time.UTC))
+	assert.True(t, daily.Contains(at(1, 5, 22), time.UTC))
+	assert.False(t, daily.Contains(time.Date(2020, 1, 5, 23, 0, 0, 0, time.UTC), time.UTC))
+
+	// sunday of every second week 02:00-04:00, counted from the week of 2018-12-31
+	weekly := maintenance(MaintenancePeriod{Type: 3, Every: 2, DayOfWeek: 64, StartTime: 2 * 3600, Period: 2 * 3600})
+	assert.True(t, weekly.Contains(at(1, 6, 3), time.UTC))
+	assert.False(t, weekly.Contains(at(1, 13, 3), time.UTC))
+	assert.True(t, weekly.Contains(at(1, 20, 3), time.UTC))
+	assert.False(t, weekly.Contains(at(1, 20, 4), time.UTC))
+
+	// the whole last friday of january and february
+	lastFriday := maintenance(MaintenancePeriod{Type: 4, Every: 5, Month: 3, DayOfWeek: 16, Period: 86400})
+	assert.True(t, lastFriday.Contains(at(1, 25, 12), time.UTC))
+	assert.False(t, lastFriday.Contains(at(1, 18, 12), time.UTC))
+	assert.False(t, lastFriday.Contains(at(3, 29, 12), time.UTC))
+
+	// the 15th of every month 01:00-02:00 in Zurich
+	fifteenth := maintenance(MaintenancePeriod{Type: 4, Month: 4095, Day: 15, StartTime: 3600, Period: 3600})
+	assert.True(t, fifteenth.Contains(at(2, 15, 1), time.UTC))
+	assert.False(t, fifteenth.Contains(at(2, 16, 1), time.UTC))
+	if zurich, err := time.LoadLocation("Europe/Zurich"); err == nil {
+		assert.True(t, fifteenth.Contains(at(2, 15, 0), zurich))
+		assert.False(t, fifteenth.Contains(at(2, 15, 1), zurich))
+	}
+}
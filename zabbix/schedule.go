@@ -0,0 +1,97 @@
+package zabbix
+
+/**
+ * Time windows like "mon-fri 08:00-18:00", "sat,sun 10:00-14:00" or "mon-fri" (whole days) in a timezone
+ */
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Schedule struct {
+	Periods  []TimePeriod
+	Location *time.Location
+}
+
+var weekdays = map[string]int{"mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6, "sun": 7}
+
+/**
+ * Parse the windows of a schedule. An empty timezone uses the local time
+ */
+func ParseSchedule(windows []string, timezone string) (Schedule, error) {
+	schedule := Schedule{Location: time.Local}
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return Schedule{}, err
+		}
+		schedule.Location = location
+	}
+	for _, window := range windows {
+		periods, err := parseWindow(window)
+		if err != nil {
+			return Schedule{}, err
+		}
+		schedule.Periods = append(schedule.Periods, periods...)
+	}
+	return schedule, nil
+}
+
+func parseWindow(window string) ([]TimePeriod, error) {
+	fields := strings.Fields(strings.ToLower(window))
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid schedule window %q", window)
+	}
+	from, to := time.Duration(0), time.Hour*24
+	if len(fields) == 2 {
+		times := strings.SplitN(fields[1], "-", 2)
+		if len(times) != 2 {
+			return nil, fmt.Errorf("invalid time range in schedule window %q", window)
+		}
+		var err error
+		if from, err = parseTimeOfDay(times[0]); err != nil {
+			return nil, err
+		}
+		if to, err = parseTimeOfDay(times[1]); err != nil {
+			return nil, err
+		}
+		if from >= to {
+			return nil, fmt.Errorf("schedule window %q ends before it starts. split windows over midnight", window)
+		}
+	}
+
+	periods := make([]TimePeriod, 0)
+	for _, days := range strings.Split(fields[0], ",") {
+		bounds := strings.SplitN(days, "-", 2)
+		first, found := weekdays[bounds[0]]
+		last := first
+		if found && len(bounds) == 2 {
+			last, found = weekdays[bounds[1]]
+		}
+		if !found || first > last {
+			return nil, fmt.Errorf("invalid days %q in schedule window %q", days, window)
+		}
+		periods = append(periods, TimePeriod{FromDay: first, ToDay: last, From: from, To: to})
+	}
+	return periods, nil
+}
+
+/**
+ * True if t is inside one of the windows. An empty schedule contains all times
+ */
+func (s Schedule) Contains(t time.Time) bool {
+	if len(s.Periods) == 0 {
+		return true
+	}
+	if s.Location != nil {
+		t = t.In(s.Location)
+	}
+	for _, period := range s.Periods {
+		if period.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
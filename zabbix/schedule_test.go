@@ -0,0 +1,42 @@
+package zabbix
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	schedule, err := ParseSchedule([]string{"mon-fri 08:00-18:00", "sat,sun 10:00-14:00"}, "UTC")
+	assert.Nil(t, err)
+	assert.Len(t, schedule.Periods, 3)
+	assert.Equal(t, TimePeriod{FromDay: 1, ToDay: 5, From: 8 * time.Hour, To: 18 * time.Hour}, schedule.Periods[0])
+	assert.Equal(t, TimePeriod{FromDay: 7, ToDay: 7, From: 10 * time.Hour, To: 14 * time.Hour}, schedule.Periods[2])
+
+	schedule, err = ParseSchedule([]string{"Tue"}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, TimePeriod{FromDay: 2, ToDay: 2, From: 0, To: 24 * time.Hour}, schedule.Periods[0])
+	assert.Equal(t, time.Local, schedule.Location)
+
+	for _, invalid := range []string{"", "fri-mon", "mon 18:00-08:00", "mon 8-18", "monday 08:00-18:00", "mon 08:00-18:00 UTC"} {
+		_, err = ParseSchedule([]string{invalid}, "")
+		assert.NotNil(t, err, invalid)
+	}
+	_, err = ParseSchedule(nil, "Nowhere/Atlantis")
+	assert.NotNil(t, err)
+}
+
+func TestScheduleContains(t *testing.T) {
+	schedule, err := ParseSchedule([]string{"mon-fri 08:00-18:00"}, "UTC")
+	assert.Nil(t, err)
+	// Monday 2019-01-07
+	assert.True(t, schedule.Contains(time.Date(2019, 1, 7, 8, 0, 0, 0, time.UTC)))
+	assert.False(t, schedule.Contains(time.Date(2019, 1, 7, 18, 0, 0, 0, time.UTC)))
+	assert.False(t, schedule.Contains(time.Date(2019, 1, 6, 12, 0, 0, 0, time.UTC)))
+	// 07:30 UTC is 08:30 in Zurich
+	zurich, err := ParseSchedule([]string{"mon-fri 08:00-18:00"}, "Europe/Zurich")
+	if err == nil {
+		assert.True(t, zurich.Contains(time.Date(2019, 1, 7, 7, 30, 0, 0, time.UTC)))
+	}
+	assert.True(t, Schedule{}.Contains(time.Now()))
+}
@@ -15,7 +15,6 @@ import (
 	"strconv"
 	"strings"
 	"time"
-	"sort"
 )
 
 /**
@@ -39,6 +38,9 @@ var templates map[string]string = make(map[string]string, 0)
 // Host ID to Host Name
 var hosts map[string]string = make(map[string]string, 0)
 
+// Host Name to derived key to value written in this run
+var emitted = make(map[string]map[string]sample)
+
 func main() {
 	var err error
 
@@ -56,6 +58,11 @@ func main() {
 	output := flag.String("output", "-", "destination for processed values")
 
 	nop := flag.Bool("nop", false, "do not publish values, even when zabbix_sender is configured")
+	stateFile := flag.String("state", "", "file keeping algorithm state between runs. overrides the configuration. not written with -nop or when sending fails")
+	recommend := flag.String("recommend", "", "write the detected seasonality as item configuration fragment to this file")
+	slaFile := flag.String("sla", "", "write the sla results as csv to this file")
+	anchor := flag.String("correlate", "", "rank the items found by the item filters by their correlation with this item key, instead of processing them")
+	reportFile := flag.String("report", "", "write the ranked pastweeks deviations to this file")
 
 	flag.Parse()
 
@@ -98,6 +105,10 @@ func main() {
 		configuration.Zabbix.Api.Username = *password
 	}
 
+	if *stateFile != "" {
+		configuration.State.File = *stateFile
+	}
+
 	if *apiUrl != "" {
 		if configuration.Zabbix.Api.URL != "" {
 			Log.Debug("api uri from command line overrides configuration value")
@@ -122,7 +133,63 @@ func main() {
 		return
 	}
 
+	err = loadCalendars(configuration.Calendars)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "cannot load calendar", err)
+		os.Exit(7)
+	}
+
+	if *anchor != "" {
+		data, err := formatCorrelations(correlate(session, configuration, *anchor), configuration.Correlation.Format)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "cannot format correlations", err)
+			os.Exit(6)
+		}
+		if *output != "-" {
+			err = ioutil.WriteFile(*output, data, 0644)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, "cannot write file ", *output, err)
+			}
+		} else {
+			os.Stdout.Write(data)
+		}
+		return
+	}
+
+	if configuration.State.File != "" {
+		err := loadState(configuration.State.File)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "cannot read state file", configuration.State.File, err)
+			os.Exit(4)
+		}
+	}
+
 	findItems(session, configuration)
+	if configuration.Health.Key != "" {
+		processHealth(configuration.Health)
+	}
+	processFormulas(session, configuration.Formulas)
+
+	if *slaFile != "" {
+		err := writeSLAReport(*slaFile)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "cannot write sla report", *slaFile, err)
+		}
+	}
+
+	if *reportFile != "" {
+		err := writeReport(*reportFile, configuration.Report)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "cannot write report", *reportFile, err)
+		}
+	}
+
+	if *recommend != "" {
+		err := writeRecommendations(*recommend)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "cannot write recommendations", *recommend, err)
+		}
+	}
 
 	if *output != "-" {
 		err := ioutil.WriteFile(*output, zabbixSenderBytes.Bytes(), 0644)
@@ -134,8 +201,21 @@ func main() {
 		io.Copy(os.Stdout, bytes.NewReader(zabbixSenderBytes.Bytes()))
 	}
 
-	if *nop == false && len(configuration.Zabbix.Sender.Host) > 0 {
-		exitCode := sendItemData(configuration, *output, *verbose)
+	if *nop {
+		// a dry run must not advance the state, the next run would skip the samples
+		return
+	}
+	exitCode := 0
+	if len(configuration.Zabbix.Sender.Host) > 0 {
+		exitCode = sendItemData(configuration, *output, *verbose)
+	}
+	if configuration.State.File != "" && exitCode == 0 {
+		err := saveState(configuration.State.File)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "cannot write state file", configuration.State.File, err)
+		}
+	}
+	if exitCode != 0 {
 		os.Exit(exitCode)
 	}
 }
@@ -192,7 +272,7 @@ func fetch(session zabbix.Session, item zabbix.ItemResponseElement, date time.Ti
 		"to", time.Unix(query.To, 0).Format("Mon 01-02 15:04:05"))
 
 	values := query.Query()
-	return values
+	return preprocessHistory(item.ItemID, values)
 }
 
 func getClosestValue(timepoint time.Time, values []zabbix.HistoryValue) zabbix.HistoryValue {
@@ -213,10 +293,19 @@ func getClosestValue(timepoint time.Time, values []zabbix.HistoryValue) zabbix.H
 
 }
 
+/**
+ * Current value and the values found at the same time in past weeks
+ */
+type weekComparison struct {
+	current   float64
+	timestamp time.Time
+	samples   []float64
+}
+
 /**
  * Fetch n weeks back.
  */
-func compareWeeks(session zabbix.Session, item zabbix.ItemResponseElement, weeks int, window time.Duration) (float64, time.Time) {
+func compareWeeks(session zabbix.Session, item zabbix.ItemResponseElement, weeks int, window time.Duration, exclude string) weekComparison {
 
 	now := time.Now()
 	// now fetch latest value
@@ -225,7 +314,7 @@ func compareWeeks(session zabbix.Session, item zabbix.ItemResponseElement, weeks
 		Log.Info("no current value found in window",
 			"from", now.Add(-window).Format("01-02 15:04:05"),
 			"to", now.Add(window).Format("01-02 15:04:05"))
-		return math.NaN(), now
+		return weekComparison{current: math.NaN(), timestamp: now}
 	}
 	current, _ := strconv.ParseFloat(values[0].Value, 64)
 	// Sample timepoint
@@ -237,10 +326,21 @@ func compareWeeks(session zabbix.Session, item zabbix.ItemResponseElement, weeks
 	tp := timestamp
 	oneWeek := time.Hour * 24 * 7
 	//oneWeek := time.Hour * 24
-	for i := 0; i < weeks; i++ {
+	// replaced weeks go back at most weeks further
+	for i, step := 0, 0; i < weeks && step < 2*weeks; step++ {
 		tp = tp.Add(-oneWeek) // step one week back
+		if excluded(item.HostID, tp) {
+			Log.Info("skipping excluded week", "date", tp.Format("Mon 01-02 15:04:05"), "exclude", exclude)
+			if exclude != "replace" {
+				i++
+			}
+			continue
+		}
+		i++
 		closest := getClosestValue(tp, fetch(session, item, tp, window))
-		if closest.Clock != 0 {
+		if closest.Clock != 0 && !inBaseline(item.ItemID, time.Unix(closest.Clock, closest.Nano)) {
+			Log.Info("ignoring historic value outside the schedule", "date", time.Unix(closest.Clock, 0).Format("Mon 01-02 15:04:05"))
+		} else if closest.Clock != 0 {
 			value, _ := strconv.ParseFloat(closest.Value, 64)
 			historicValues = append(historicValues, value)
 			when := time.Unix(closest.Clock, closest.Nano)
@@ -253,25 +353,10 @@ func compareWeeks(session zabbix.Session, item zabbix.ItemResponseElement, weeks
 	historic := average(historicValues)
 	Log.Info("calculation done", log.Ctx{"average": historic, "current": current, "difference": current - historic})
 
-	return current - historic, timestamp
-
-}
-
-func average(values []float64) float64 {
-	if len(values) > 2 {
-		sort.Float64s(values)
-		values = values[1 : len(values)-1]
-	}
+	return weekComparison{current: current, timestamp: timestamp, samples: historicValues}
 
-	sum := float64(0)
-
-	for _, value := range values{
-		sum = sum + value
-	}
-	return sum / float64(len(values))
 }
 
-
 /**
  * Find matching Hosts by template filter
  */
@@ -291,6 +376,14 @@ func collectHostsByTemplate(session zabbix.Session, configuration zabbix.Configu
 	}
 }
 
+func recordGroups(host zabbix.HostResponseElement) {
+	groups := make([]string, len(host.Groups))
+	for i, group := range host.Groups {
+		groups[i] = group.Name
+	}
+	hostGroups[host.HostID] = groups
+}
+
 /**
  * Collect host details
  */
@@ -303,6 +396,7 @@ func collectHosts(session zabbix.Session, configuration zabbix.Configuration) {
 		hostElements := hostQuery.Query()
 		for _, hostElement := range hostElements {
 			hosts[hostElement.HostID] = hostElement.Name
+			recordGroups(hostElement)
 		}
 
 		Log.Debug("collected hosts via template lookup", "hosts", hosts)
@@ -316,6 +410,7 @@ func collectHosts(session zabbix.Session, configuration zabbix.Configuration) {
 			hostElements := hostQuery.Query()
 			for _, hostElement := range hostElements {
 				hosts[hostElement.HostID] = hostElement.Name
+				recordGroups(hostElement)
 			}
 			Log.Debug("collected hosts via host filter", "hosts", hosts, "index", index)
 		}
@@ -339,6 +434,9 @@ func findItems(session zabbix.Session, configuration zabbix.Configuration) {
 		query := session.NewItemQuery(keysFromMap(hosts), itemFilter.Filter, itemFilter.Search)
 		query.SearchWildcardsEnabled = true
 		items := query.Query()
+		if len(itemFilter.Parameters) > 0 {
+			items = filterByParameters(items, itemFilter.Parameters)
+		}
 
 		if len(items) > 0 {
 			// find all active hosts
@@ -349,25 +447,245 @@ func findItems(session zabbix.Session, configuration zabbix.Configuration) {
 	}
 }
 
+/**
+ * Items whose key parameters match the patterns
+ */
+func filterByParameters(items []zabbix.ItemResponseElement, parameters map[int]string) []zabbix.ItemResponseElement {
+	matching := make([]zabbix.ItemResponseElement, 0, len(items))
+	for _, item := range items {
+		key, err := zabbix.ParseKey(item.Key)
+		if err != nil {
+			Log.Warn("skipping item with invalid key", "itemid", item.ItemID, "key", item.Key, "error", err)
+			continue
+		}
+		if key.MatchesParameters(parameters) {
+			matching = append(matching, item)
+		}
+	}
+	return matching
+}
+
 func processItems(session zabbix.Session, items []zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	schedule, err := zabbix.ParseSchedule(itemConfiguration.Schedule.Windows, itemConfiguration.Schedule.Timezone)
+	if err != nil {
+		Log.Error("skipping items with invalid schedule", "schedule", itemConfiguration.Schedule, "error", err)
+		return
+	}
+
+	defer applyPreprocessing(items, itemConfiguration.Preprocessing)()
+
+	// the schedule state of the items is needed by the group algorithms as well
+	now := time.Now()
+	scheduled := make([]zabbix.ItemResponseElement, 0, len(items))
+	for _, item := range items {
+		if scheduleItem(item, itemConfiguration.Schedule, schedule, now) {
+			scheduled = append(scheduled, item)
+		}
+	}
+	items = scheduled
+
+	if itemConfiguration.Peers.Window > 0 {
+		processPeers(session, items, itemConfiguration)
+	}
+
+	if itemConfiguration.Aggregate.Host != "" {
+		processAggregate(session, items, itemConfiguration.Aggregate)
+	}
+
 	for index, item := range items {
 		Log.Info(fmt.Sprintf("processing item %d/%d", index, len(items)), "itemid", item.ItemID, "key", item.Key, "data", item)
-		if itemConfiguration.PastWeeks.Weeks > 0 {
-
-			halfWindow := time.Duration(itemConfiguration.PastWeeks.Window / 2)
-			value, timestamp := compareWeeks(session, item, itemConfiguration.PastWeeks.Weeks, halfWindow*time.Second)
-			if math.IsNaN(value) == false {
-				addSenderLine(hosts[item.HostID], item.Key, itemConfiguration.Postfix, timestamp, value)
-			} else {
-				Log.Warn("skipping item due to missing data", "item", item)
-			}
+
+		configuration := itemConfiguration
+		if configuration.Seasonality.Lookback > 0 {
+			configuration = processSeasonality(session, item, itemConfiguration)
 		}
+
+		if configuration.PastWeeks.Weeks > 0 {
+			processPastWeeks(session, item, configuration)
+		}
+
+		if configuration.HoltWinters.Lookback > 0 {
+			processHoltWinters(session, item, configuration)
+		}
+
+		if configuration.STL.Lookback > 0 {
+			processDecomposition(session, item, configuration)
+		}
+
+		if configuration.Forecast.Lookback > 0 {
+			processForecast(session, item, configuration)
+		}
+
+		if configuration.EWMA.Alpha > 0 {
+			processEWMA(session, item, configuration)
+		}
+
+		if configuration.ChangePoint.Lookback > 0 {
+			processChangePoint(session, item, configuration)
+		}
+
+		if configuration.Quality.Window > 0 {
+			processQuality(session, item, configuration)
+		}
+
+		if configuration.Distribution.Window > 0 {
+			processDistribution(session, item, configuration)
+		}
+
+		if configuration.Log.Interval > 0 {
+			processLog(session, item, configuration)
+		}
+
+		if configuration.TextChange.Weeks > 0 {
+			processTextChange(session, item, configuration)
+		}
+
+		if configuration.SLA.Period != "" {
+			processSLA(session, item, configuration)
+		}
+	}
+}
+
+/**
+ * Half of the configured search window. Without configured window, one update interval of the item
+ */
+func pastWeeksWindow(session zabbix.Session, item zabbix.ItemResponseElement, configuration zabbix.PastWeeksAlgorithmConfiguration) (time.Duration, bool) {
+	if configuration.Window > 0 {
+		return time.Duration(configuration.Window/2) * time.Second, true
+	}
+	delay, err := session.ItemDelay(item)
+	if err != nil || delay.IntervalAt(time.Now()) == 0 {
+		Log.Warn("skipping item without window and usable delay", "item", item.ItemID, "delay", item.Delay, "error", err)
+		return 0, false
+	}
+	return delay.IntervalAt(time.Now()), true
+}
+
+/**
+ * Compare the current value with the same time in the past weeks
+ */
+func processPastWeeks(session zabbix.Session, item zabbix.ItemResponseElement, itemConfiguration zabbix.ItemConfiguration) {
+	halfWindow, found := pastWeeksWindow(session, item, itemConfiguration.PastWeeks)
+	if !found {
+		return
+	}
+	comparison := compareWeeks(session, item, itemConfiguration.PastWeeks.Weeks, halfWindow, itemConfiguration.PastWeeks.Exclude)
+	if math.IsNaN(comparison.current) {
+		Log.Warn("skipping item due to missing data", "item", item)
+		return
+	}
+
+	outputs := itemConfiguration.PastWeeks.Outputs
+	if len(outputs) == 0 {
+		// the absolute deviation under the plain item postfix, not postfix.absolute like other defaults
+		outputs = []zabbix.OutputConfiguration{{Type: "absolute", Postfix: itemConfiguration.Postfix, Key: itemConfiguration.KeyTemplate}}
+	} else {
+		outputs = outputsOrDefault(outputs, itemConfiguration)
+	}
+	values := deviations(comparison.current, comparison.samples)
+	recordDeviation(item, comparison, values)
+	recordDeviationScore(item, values)
+	for output, value := range band(comparison.samples, itemConfiguration.PastWeeks.Bands) {
+		values[output] = value
+	}
+	emitOutputs(item, outputs, comparison.timestamp, values)
+}
+
+/**
+ * Configured outputs or all given types, postfixed with the item postfix and the type name.
+ * Outputs without key template use the template of the item configuration
+ */
+func outputsOrDefault(outputs []zabbix.OutputConfiguration, itemConfiguration zabbix.ItemConfiguration, types ...string) []zabbix.OutputConfiguration {
+	result := make([]zabbix.OutputConfiguration, 0, len(outputs)+len(types))
+	result = append(result, outputs...)
+	if len(result) == 0 {
+		for _, t := range types {
+			result = append(result, zabbix.OutputConfiguration{Type: t, Postfix: itemConfiguration.Postfix + "." + t})
+		}
+	}
+	for i := range result {
+		if result[i].Key == "" {
+			result[i].Key = itemConfiguration.KeyTemplate
+		}
+	}
+	return result
+}
+
+/**
+ * Write the configured outputs of an algorithm. Values which are not a number are skipped
+ */
+func emitOutputs(item zabbix.ItemResponseElement, outputs []zabbix.OutputConfiguration, timestamp time.Time, values map[string]float64) {
+	for _, output := range outputs {
+		value, found := values[output.Type]
+		if !found {
+			Log.Warn("unknown output type", "type", output.Type, "key", item.Key)
+			continue
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			Log.Warn("skipping output without value", "type", output.Type, "key", item.Key)
+			continue
+		}
+		key, ok := derivedKey(item.Key, output.Postfix, output.Key)
+		if !ok {
+			continue
+		}
+		if neutral, found := neutralValue(item.ItemID, output.Type); found {
+			value = neutral
+		} else if scoreTypes[output.Type] {
+			recordScore(item, value)
+		}
+		addSenderLine(hosts[item.HostID], key, timestamp, value)
 	}
 }
 
-func addSenderLine(hostname string, key string, postfix string, timestamp time.Time, value float64) {
-	newKey := strings.Replace(key, "[", postfix + "[" ,1)
-	line := fmt.Sprintf("\"%s\" %s %d %f\n", hostname, newKey, timestamp.Unix(), value)
+/**
+ * Key of a derived item, see zabbix.ItemKey.Derive for the template. False if the template cannot be resolved,
+ * the output is skipped then
+ */
+func derivedKey(key string, postfix string, template string) (string, bool) {
+	parsed, err := zabbix.ParseKey(key)
+	if err != nil {
+		Log.Warn("cannot parse item key, inserting postfix before the parameters", "key", key, "error", err)
+		return strings.Replace(key, "[", postfix+"[", 1), true
+	}
+	derived, err := parsed.Derive(template, postfix)
+	if err != nil {
+		Log.Warn("skipping output with unresolved key template", "key", key, "template", template, "error", err)
+		return "", false
+	}
+	return derived, true
+}
+
+func addSenderLine(hostname string, key string, timestamp time.Time, value float64) {
+	writeSenderLine(hostname, key, timestamp, fmt.Sprintf("%f", value))
+	if emitted[hostname] == nil {
+		emitted[hostname] = make(map[string]sample)
+	}
+	emitted[hostname][key] = sample{time: timestamp, value: value}
+}
+
+/**
+ * Text value for character and text items
+ */
+func addSenderText(hostname string, key string, timestamp time.Time, value string) {
+	writeSenderLine(hostname, key, timestamp, senderQuote(value))
+}
+
+// quote values with whitespace, quotes or backslashes for zabbix_sender.
+// the input file has one value per line, line breaks become spaces
+func senderQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\r\n\"\\") {
+		return value
+	}
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\r\n", " ", "\n", " ", "\r", " ").Replace(value) + "\""
+}
+
+func writeSenderLine(hostname string, key string, timestamp time.Time, value string) {
+	quoted := key
+	if strings.ContainsAny(key, " \t") {
+		quoted = "\"" + strings.Replace(key, "\"", "\\\"", -1) + "\""
+	}
+	line := fmt.Sprintf("\"%s\" %s %d %s\n", hostname, quoted, timestamp.Unix(), value)
 	_, err := zabbixSenderBytes.WriteString(line)
 	if err != nil {
 		Log.Warn("error writing item data", "error", err)
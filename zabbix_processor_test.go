@@ -1,13 +1,70 @@
 package main
 
 import (
+	"encoding/json"
 	"github.com/cbuehlmann/zabbixtools/zabbix"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
+/**
+ * Session of a fake ZABBIX API answering each request with the result of the handler
+ */
+func fakeAPI(t *testing.T, handler func(method string, params map[string]interface{}) interface{}) zabbix.Session {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string
+			Params map[string]interface{}
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": handler(request.Method, request.Params)})
+	}))
+	t.Cleanup(server.Close)
+	return zabbix.Session{URL: server.URL}
+}
+
 func TestZabbixSenderInMain(t *testing.T) {
 	configuration := zabbix.Configuration{}
 	configuration.Zabbix.Sender.Host = "192.168.109.51"
 	configuration.Zabbix.Sender.Binary = "D:/tools/zabbix_sender.exe"
 	sendItemData(configuration, "out.zbx", true)
 }
+
+func TestDerivedKey(t *testing.T) {
+	derived := func(key string, postfix string, template string) string {
+		result, ok := derivedKey(key, postfix, template)
+		assert.True(t, ok)
+		return result
+	}
+	assert.Equal(t, "system.cpu.3wd[,idle]", derived("system.cpu[,idle]", ".3wd", ""))
+	assert.Equal(t, "system.uptime.3wd", derived("system.uptime", ".3wd", ""))
+	assert.Equal(t, `web.page.3wd["http://x/[a]"]`, derived(`web.page["http://x/[a]"]`, ".3wd", ""))
+	assert.Equal(t, "baseline[system.cpu,idle]", derived("system.cpu[,idle]", ".3wd", "baseline[{key},{param2}]"))
+
+	_, ok := derivedKey("system.cpu[,idle]", ".3wd", "baseline[{param3}]")
+	assert.False(t, ok)
+}
+
+func TestEmitOutputsSkipsUnresolvedKeys(t *testing.T) {
+	defer func() {
+		emitted = make(map[string]map[string]sample)
+		zabbixSenderBytes.Reset()
+	}()
+	hosts["1"] = "web01"
+	defer delete(hosts, "1")
+	outputs := []zabbix.OutputConfiguration{{Type: "absolute", Postfix: ".3wd"}, {Type: "percent", Key: "{key}.pct[{param2}]"}}
+	emitOutputs(zabbix.ItemResponseElement{ItemID: "1", HostID: "1", Key: "system.cpu[all]"}, outputs, time.Unix(100, 0),
+		map[string]float64{"absolute": 1, "percent": 2})
+	assert.Equal(t, map[string]sample{"system.cpu.3wd[all]": {time: time.Unix(100, 0), value: 1}}, emitted["web01"])
+	assert.NotContains(t, zabbixSenderBytes.String(), "{param2}")
+}
+
+func TestFilterByParameters(t *testing.T) {
+	items := []zabbix.ItemResponseElement{{ItemID: "1", Key: "vfs.fs.size[/,free]"}, {ItemID: "2", Key: "vfs.fs.size[/var,used]"}, {ItemID: "3", Key: "vfs.fs.size[/,"}}
+	filtered := filterByParameters(items, map[int]string{2: "free"})
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, "1", filtered[0].ItemID)
+}